@@ -4,11 +4,18 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"card-manager/internal/pkg/cardfile"
 	"card-manager/localizer"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "extract-json" {
+		runExtractJSON(os.Args[2:])
+		return
+	}
+
 	checkFlag := flag.Bool("check", false, "Check if localization is needed")
 	basePathFlag := flag.String("base-path", "", "SillyTavern's public folder path")
 	proxyFlag := flag.String("proxy", "", "Proxy address, e.g., http://127.0.0.1:7890")
@@ -46,3 +53,42 @@ func main() {
 		}
 	}
 }
+
+// runExtractJSON 从 PNG 或 CHARX 角色卡中导出格式化的 JSON
+func runExtractJSON(args []string) {
+	fs := flag.NewFlagSet("extract-json", flag.ExitOnError)
+	outputFlag := fs.String("o", "", "Output file path, '-' for stdout (default: <card name>.json next to the card)")
+	forceFlag := fs.Bool("f", false, "Overwrite the output file if it already exists")
+	fs.Parse(args)
+
+	cardPath := fs.Arg(0)
+	if cardPath == "" {
+		fmt.Fprintln(os.Stderr, "Usage: cli extract-json [-o output] [-f] <card path>")
+		os.Exit(1)
+	}
+
+	data, err := cardfile.PrettyJSON(cardPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "导出失败:", err)
+		os.Exit(1)
+	}
+
+	if *outputFlag == "-" {
+		os.Stdout.Write(data)
+		return
+	}
+
+	outputPath := *outputFlag
+	if outputPath == "" {
+		outputPath = filepath.Join(filepath.Dir(cardPath), cardfile.JSONFileName(cardPath))
+	}
+	if _, err := os.Stat(outputPath); err == nil && !*forceFlag {
+		fmt.Fprintln(os.Stderr, "输出文件已存在，使用 -f 覆盖:", outputPath)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "写入文件失败:", err)
+		os.Exit(1)
+	}
+	fmt.Println("已导出:", outputPath)
+}
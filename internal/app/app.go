@@ -4,18 +4,29 @@ import (
 	"card-manager/internal/config"
 	"card-manager/internal/handlers"
 	"card-manager/internal/pkg/cache"
+	"card-manager/internal/pkg/collections"
+	"card-manager/internal/pkg/fulltext"
+	"card-manager/internal/pkg/journal"
+	"card-manager/internal/pkg/tags"
 	"card-manager/internal/pkg/tavern"
+	"card-manager/internal/pkg/trash"
 	"fmt"
 	"io/fs"
 	"log/slog"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"time"
 )
 
+// trashPurgeInterval 自动清理回收站过期条目的间隔
+const trashPurgeInterval = time.Hour
+
 // App 应用程序结构体，包含所有依赖
 type App struct {
 	Config        *config.Config
 	CacheManager  *cache.Manager
+	SearchIndex   *fulltext.Index
 	Handlers      *handlers.Handlers
 	TavernScanner *tavern.Scanner
 }
@@ -25,11 +36,26 @@ func NewApp(cfg *config.Config) *App {
 	// 初始化缓存管理器
 	cacheManager := cache.NewManager("cache.json")
 
+	// 初始化全文索引，与缓存文件放在一起
+	searchIndex := fulltext.New("search_index.json")
+
 	// 初始化Tavern扫描器
 	tavernScanner := tavern.NewScanner(cfg.TavernCharactersPath)
 
+	// 初始化回收站
+	trashBin := trash.New(cfg.TrashPath())
+
+	// 初始化操作日志
+	operations := journal.New(filepath.Join(cfg.LibraryDataPath(), "journal.jsonl"), trashBin)
+
+	// 初始化标签登记表
+	tagStore := tags.New(filepath.Join(cfg.LibraryDataPath(), "tags.json"))
+
+	// 初始化智能收藏夹
+	collectionStore := collections.New(filepath.Join(cfg.LibraryDataPath(), "collections.json"))
+
 	// 初始化处理器
-	handlers := handlers.NewHandlers(cfg, cacheManager)
+	handlers := handlers.NewHandlers(cfg, cacheManager, trashBin, operations, searchIndex, tagStore, collectionStore)
 	
 	// 设置Tavern扫描器
 	handlers.SetTavernScanner(tavernScanner)
@@ -37,6 +63,7 @@ func NewApp(cfg *config.Config) *App {
 	return &App{
 		Config:        cfg,
 		CacheManager:  cacheManager,
+		SearchIndex:   searchIndex,
 		Handlers:      handlers,
 		TavernScanner: tavernScanner,
 	}
@@ -51,6 +78,11 @@ func (a *App) Initialize() error {
 		slog.Info("✓ 缓存加载完成")
 	}
 
+	// 加载全文索引
+	if err := a.SearchIndex.Load(); err != nil {
+		slog.Warn("全文索引加载失败，将在扫描时重建", "error", err)
+	}
+
 	// 扫描Tavern哈希
 	if err := a.TavernScanner.ScanHashes(); err != nil {
 		slog.Warn("Tavern目录扫描失败", "error", err)
@@ -58,6 +90,14 @@ func (a *App) Initialize() error {
 		slog.Info("✓ Tavern目录扫描完成")
 	}
 
+	// 定期清理回收站中超过保留期限的条目
+	a.Handlers.Trash.PurgeExpired()
+	go func() {
+		for range time.Tick(trashPurgeInterval) {
+			a.Handlers.Trash.PurgeExpired()
+		}
+	}()
+
 	return nil
 }
 
@@ -70,7 +110,20 @@ func (a *App) SetupRoutes(staticFS fs.FS) {
 	// 卡片管理相关路由
 	http.HandleFunc("/api/cards", a.withMiddleware(a.Handlers.Cards.GetCards))
 	http.HandleFunc("/api/scan-changes", a.withMiddleware(a.Handlers.Cards.ScanChanges))
+	http.HandleFunc("/api/characters", a.withMiddleware(a.Handlers.Cards.ListCharacters))
+	http.HandleFunc("/api/character", a.withMiddleware(a.Handlers.Cards.GetCharacter))
 	http.HandleFunc("/api/stats", a.withMiddleware(a.Handlers.Cards.GetStats))
+	http.HandleFunc("/api/integrity-report", a.withMiddleware(a.Handlers.Cards.GetIntegrityReport))
+	http.HandleFunc("/api/edit-card", a.withMiddleware(a.Handlers.Cards.EditCard))
+	http.HandleFunc("/api/diff-versions", a.withMiddleware(a.Handlers.Cards.DiffVersions))
+	http.HandleFunc("/api/version-note", a.withMiddleware(a.Handlers.Cards.SetVersionNote))
+	http.HandleFunc("/api/current-version", a.withMiddleware(a.Handlers.Cards.SetCurrentVersion))
+	http.HandleFunc("/api/duplicates", a.withMiddleware(a.Handlers.Cards.GetDuplicates))
+	http.HandleFunc("/api/resolve-duplicates", a.withMiddleware(a.Handlers.Cards.ResolveDuplicates))
+	http.HandleFunc("/api/similar-images", a.withMiddleware(a.Handlers.Cards.SimilarImages))
+	http.HandleFunc("/api/find-by-image", a.withMiddleware(a.Handlers.Cards.FindByImage))
+	http.HandleFunc("/api/rename-character", a.withMiddleware(a.Handlers.Cards.RenameCharacter))
+	http.HandleFunc("/api/search", a.withMiddleware(a.Handlers.Cards.SearchContent))
 	
 	// 文件操作相关路由
 	http.HandleFunc("/api/image", a.withMiddleware(a.Handlers.Files.GetImage))
@@ -80,8 +133,51 @@ func (a *App) SetupRoutes(staticFS fs.FS) {
 	http.HandleFunc("/api/move-character", a.withMiddleware(a.Handlers.Files.MoveCharacter))
 	http.HandleFunc("/api/organize-stray", a.withMiddleware(a.Handlers.Files.OrganizeStray))
 	http.HandleFunc("/api/delete-stray", a.withMiddleware(a.Handlers.Files.DeleteStray))
+	http.HandleFunc("/api/delete-character", a.withMiddleware(a.Handlers.Files.DeleteCharacter))
 	http.HandleFunc("/api/list-files", a.withMiddleware(a.Handlers.Files.ListFiles))
 	http.HandleFunc("/api/merge-json-to-png", a.withMiddleware(a.Handlers.Files.MergeJsonToPng))
+	http.HandleFunc("/api/convert-card", a.withMiddleware(a.Handlers.Files.ConvertCard))
+	http.HandleFunc("/api/charx-asset", a.withMiddleware(a.Handlers.Files.GetCharxAsset))
+	http.HandleFunc("/api/extract-card-json", a.withMiddleware(a.Handlers.Files.ExtractCardJson))
+	http.HandleFunc("/api/replace-avatar", a.withMiddleware(a.Handlers.Files.ReplaceAvatar))
+	
+	// 世界书相关路由
+	http.HandleFunc("/api/lorebook", a.withMiddleware(a.Handlers.Lorebook.GetLorebook))
+	http.HandleFunc("/api/export-lorebook", a.withMiddleware(a.Handlers.Lorebook.ExportLorebook))
+	http.HandleFunc("/api/attach-lorebook", a.withMiddleware(a.Handlers.Lorebook.AttachLorebook))
+	
+	// 回收站相关路由
+	http.HandleFunc("/api/trash", a.withMiddleware(a.Handlers.Trash.ListTrash))
+	http.HandleFunc("/api/trash/restore", a.withMiddleware(a.Handlers.Trash.RestoreTrash))
+	http.HandleFunc("/api/trash/purge", a.withMiddleware(a.Handlers.Trash.PurgeTrash))
+	
+	// 分类管理相关路由
+	http.HandleFunc("/api/categories", a.withMiddleware(a.Handlers.Categories.ListCategories))
+	http.HandleFunc("/api/categories/create", a.withMiddleware(a.Handlers.Categories.CreateCategory))
+	http.HandleFunc("/api/categories/rename", a.withMiddleware(a.Handlers.Categories.RenameCategory))
+	http.HandleFunc("/api/categories/merge", a.withMiddleware(a.Handlers.Categories.MergeCategories))
+	http.HandleFunc("/api/categories/delete", a.withMiddleware(a.Handlers.Categories.DeleteCategory))
+	
+	// 标签相关路由
+	http.HandleFunc("/api/tags", a.withMiddleware(a.Handlers.Cards.ListTags))
+	http.HandleFunc("/api/tags/create", a.withMiddleware(a.Handlers.Cards.CreateTag))
+	http.HandleFunc("/api/tags/rename", a.withMiddleware(a.Handlers.Cards.RenameTag))
+	http.HandleFunc("/api/tags/merge", a.withMiddleware(a.Handlers.Cards.MergeTags))
+	http.HandleFunc("/api/tags/delete", a.withMiddleware(a.Handlers.Cards.DeleteTag))
+	http.HandleFunc("/api/character-tags", a.withMiddleware(a.Handlers.Cards.SetCharacterTags))
+	http.HandleFunc("/api/character-state", a.withMiddleware(a.Handlers.Cards.SetCharacterState))
+	
+	// 智能收藏夹相关路由
+	http.HandleFunc("/api/collections", a.withMiddleware(a.Handlers.Cards.ListCollections))
+	http.HandleFunc("/api/collections/save", a.withMiddleware(a.Handlers.Cards.SaveCollection))
+	http.HandleFunc("/api/collections/rename", a.withMiddleware(a.Handlers.Cards.RenameCollection))
+	http.HandleFunc("/api/collections/delete", a.withMiddleware(a.Handlers.Cards.DeleteCollection))
+	http.HandleFunc("/api/collections/export", a.withMiddleware(a.Handlers.Cards.ExportCollections))
+	http.HandleFunc("/api/collections/import", a.withMiddleware(a.Handlers.Cards.ImportCollections))
+	
+	// 操作日志相关路由
+	http.HandleFunc("/api/journal", a.withMiddleware(a.Handlers.Journal.ListJournal))
+	http.HandleFunc("/api/undo", a.withMiddleware(a.Handlers.Journal.Undo))
 	
 	// Tavern集成相关路由
 	http.HandleFunc("/api/localize-card", a.withMiddleware(a.Handlers.Tavern.LocalizeCard))
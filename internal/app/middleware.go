@@ -139,14 +139,32 @@ func needsPathValidation(path string) bool {
 		"/api/move-character",
 		"/api/organize-stray",
 		"/api/delete-stray",
+		"/api/delete-character",
 		"/api/faces",
 		"/api/note",
 		"/api/list-files",
 		"/api/merge-json-to-png",
+		"/api/convert-card",
+		"/api/charx-asset",
+		"/api/extract-card-json",
+		"/api/replace-avatar",
+		"/api/lorebook",
+		"/api/export-lorebook",
+		"/api/attach-lorebook",
+		"/api/edit-card",
+		"/api/version-note",
+		"/api/current-version",
+		"/api/resolve-duplicates",
+		"/api/similar-images",
+		"/api/rename-character",
+		"/api/character",
+		"/api/character-tags",
+		"/api/character-state",
 	}
 	
+	// 按完整路径匹配，避免 /api/character 误匹配 /api/characters 等同前缀的端点
 	for _, endpoint := range pathValidationEndpoints {
-		if strings.HasPrefix(path, endpoint) {
+		if path == endpoint {
 			return true
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 	
 	"gopkg.in/yaml.v3"
 )
@@ -28,6 +29,8 @@ type Config struct {
 	Port                 int    `yaml:"端口" json:"port"`
 	// 代理地址 - 网络请求使用的代理服务器地址
 	Proxy                string `yaml:"代理地址" json:"proxy"`
+	// 回收站保留天数 - 删除的文件在回收站中保留的天数，0 为默认的 30 天，负数表示不自动清理
+	TrashRetentionDays   int    `yaml:"回收站保留天数" json:"trashRetentionDays"`
 	// 本地化工具配置
 	Localizer            LocalizerConfig `yaml:"本地化工具" json:"localizer"`
 }
@@ -90,4 +93,29 @@ func (pb *PathBuilder) BuildNikoPath() string {
 // 从配置创建路径构建器
 func (c *Config) NewPathBuilder() *PathBuilder {
 	return NewPathBuilder(c.TavernPublicPath)
-}
\ No newline at end of file
+}
+
+// 默认的回收站保留天数
+const defaultTrashRetentionDays = 30
+
+// 角色库元数据目录 - 位于角色卡根目录下，存放回收站等数据，扫描分类时会跳过
+func (c *Config) LibraryDataPath() string {
+	return filepath.Join(c.CharactersRootPath, ".card-manager")
+}
+
+// 回收站目录
+func (c *Config) TrashPath() string {
+	return filepath.Join(c.LibraryDataPath(), "trash")
+}
+
+// 回收站保留时长，返回 0 表示不自动清理
+func (c *Config) TrashRetention() time.Duration {
+	days := c.TrashRetentionDays
+	if days < 0 {
+		return 0
+	}
+	if days == 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
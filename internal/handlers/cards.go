@@ -4,14 +4,16 @@ import (
 	"card-manager/internal/config"
 	"card-manager/internal/models"
 	"card-manager/internal/pkg/cache"
+	"card-manager/internal/pkg/collections"
+	"card-manager/internal/pkg/journal"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/fulltext"
 	"card-manager/internal/pkg/localization"
-	"card-manager/internal/pkg/png"
+	"card-manager/internal/pkg/sidecar"
+	"card-manager/internal/pkg/tags"
 	"card-manager/internal/pkg/tavern"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/json"
+	"card-manager/internal/pkg/trash"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -22,27 +24,52 @@ import (
 	"time"
 )
 
+// metadataSchema 缓存条目的元数据版本，缓存中增加新字段或解析规则变化时递增
+const metadataSchema = 6
+
 // CardsHandler 处理卡片相关的API请求
 type CardsHandler struct {
 	config        *config.Config
 	cacheManager  *cache.Manager
 	tavernScanner *tavern.Scanner
+	trash         *trash.Bin
+	journal       *journal.Journal
+	index         *fulltext.Index
+	tags          *tags.Store
+	collections   *collections.Store
+	listing       *characterListing
 }
 
 // NewCardsHandler 创建新的卡片处理器
-func NewCardsHandler(config *config.Config, cacheManager *cache.Manager, tavernScanner *tavern.Scanner) *CardsHandler {
+func NewCardsHandler(config *config.Config, cacheManager *cache.Manager, tavernScanner *tavern.Scanner, trashBin *trash.Bin, operations *journal.Journal, index *fulltext.Index, tagStore *tags.Store, collectionStore *collections.Store) *CardsHandler {
 	return &CardsHandler{
 		config:        config,
 		cacheManager:  cacheManager,
 		tavernScanner: tavernScanner,
+		trash:         trashBin,
+		journal:       operations,
+		index:         index,
+		tags:          tagStore,
+		collections:   collectionStore,
+		listing:       &characterListing{entries: make(map[string]listingEntry)},
 	}
 }
 
-// GetCards 获取所有卡片数据
+// GetCards 获取所有卡片数据，可以用查询参数筛选角色
+//
+// 支持的参数: q（搜索文件夹名、角色卡内的名称和作者）、category（包括子分类）、
+// import、localization、hasNote、hasFaces、modifiedSince、tag（可重复）、favorite、minRating、
+// status（可重复或以逗号分隔），取值见 filter.go；collection 使用智能收藏夹的筛选条件。
 func (h *CardsHandler) GetCards(w http.ResponseWriter, r *http.Request) {
 	defer h.cacheManager.Save()
+	defer h.index.Save()
 	
-	response, err := h.fetchCardsData()
+	filter, err := h.requestFilter(r.URL.Query())
+	if err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	response, err := h.fetchFilteredCards(filter)
 	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "获取卡片数据失败", err)
 		return
@@ -54,6 +81,7 @@ func (h *CardsHandler) GetCards(w http.ResponseWriter, r *http.Request) {
 // ScanChanges 扫描变更并获取卡片数据
 func (h *CardsHandler) ScanChanges(w http.ResponseWriter, r *http.Request) {
 	defer h.cacheManager.Save()
+	defer h.index.Save()
 	
 	// 扫描Tavern哈希
 	if h.tavernScanner != nil {
@@ -73,28 +101,29 @@ func (h *CardsHandler) ScanChanges(w http.ResponseWriter, r *http.Request) {
 
 // GetStats 获取统计信息
 func (h *CardsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
-	cardsData, err := h.fetchCardsData()
+	defer h.cacheManager.Save()
+
+	_, characters, err := h.collectCharacters(nil)
 	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "无法获取卡片数据", err)
 		return
 	}
 	
 	stats := models.StatsResponse{}
-	for _, category := range cardsData.Categories {
-		for _, character := range category {
-			stats.TotalCharacters++
-			if character.LocalizationNeeded != nil && *character.LocalizationNeeded {
-				stats.NeedsLocalization++
-				if !character.IsLocalized {
-					stats.NotLocalized++
-				}
-			}
-			if !character.ImportInfo.IsImported {
-				stats.NotImported++
-			} else if !character.ImportInfo.IsLatestImported {
-				stats.NotLatestImported++
+	for _, listed := range characters {
+		character := listed.character
+		stats.TotalCharacters++
+		if character.LocalizationNeeded != nil && *character.LocalizationNeeded {
+			stats.NeedsLocalization++
+			if !character.IsLocalized {
+				stats.NotLocalized++
 			}
 		}
+		if !character.ImportInfo.IsImported {
+			stats.NotImported++
+		} else if !character.ImportInfo.IsLatestImported {
+			stats.NotLatestImported++
+		}
 	}
 	
 	writeSuccessResponse(w, "获取统计信息成功", stats)
@@ -102,62 +131,130 @@ func (h *CardsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 
 // fetchCardsData 获取卡片数据的核心逻辑
 func (h *CardsHandler) fetchCardsData() (models.CardsResponse, error) {
+	return h.fetchFilteredCards(nil)
+}
+
+// fetchFilteredCards 获取满足筛选条件的卡片数据，filter 为 nil 时返回全部
+//
+// 按分类筛选时 Categories 只包含筛选的分类及其子分类，CategoryTree 始终是完整的。
+func (h *CardsHandler) fetchFilteredCards(filter *cardFilter) (models.CardsResponse, error) {
 	response := models.CardsResponse{
-		Categories: make(map[string][]models.Character),
-		StrayCards: make([]models.StrayCard, 0),
+		Categories:   make(map[string][]models.Character),
+		StrayCards:   make([]models.StrayCard, 0),
+		CategoryTree: make([]models.CategoryNode, 0),
+	}
+
+	scan, characters, err := h.collectCharacters(filter)
+	if err != nil {
+		return response, err
+	}
+	for _, stray := range scan.strays {
+		if filter.matchStray(stray) {
+			response.StrayCards = append(response.StrayCards, stray)
+		}
 	}
+	response.CategoryTree = scan.tree
+	for _, categoryPath := range categoryPaths(scan.tree) {
+		if filter.matchCategory(categoryPath) {
+			response.Categories[categoryPath] = make([]models.Character, 0)
+		}
+	}
+	for _, listed := range characters {
+		response.Categories[listed.category] = append(response.Categories[listed.category], *listed.character)
+	}
+	return response, nil
+}
+
+// listedCharacter 读取过的角色及其所在分类
+type listedCharacter struct {
+	character *models.Character
+	category  string
+}
+
+// collectCharacters 扫描角色库并读取满足筛选条件的角色，filter 为 nil 时返回全部
+//
+// 分类不符的角色文件夹不会被读取；其余条件依据缓存的元数据判断。
+// 文件夹内容没有变化的角色直接使用上次读取的结果，返回的角色信息不能修改。
+func (h *CardsHandler) collectCharacters(filter *cardFilter) (*libraryScan, []listedCharacter, error) {
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
-	rootDirents, err := os.ReadDir(h.config.CharactersRootPath)
+	scan, err := scanLibrary(h.config.CharactersRootPath)
 	if err != nil {
-		slog.Error("📂 无法读取角色根目录", "路径", h.config.CharactersRootPath, "error", err)
-		return response, fmt.Errorf("无法读取角色根目录: %w", err)
+		return nil, nil, err
 	}
+	external := h.listingStamp()
+	h.listing.prune(scan.characters)
 
-	for _, dirent := range rootDirents {
-		if !dirent.IsDir() {
+	characters := make([]listedCharacter, 0, len(scan.characters))
+	for _, folder := range scan.characters {
+		if !filter.matchCategory(folder.category) {
 			continue
 		}
+		wg.Add(1)
+		go func(folder characterFolder) {
+			defer wg.Done()
+			character := h.listedCharacter(folder.path, external)
+			if character != nil && filter.matchCharacter(character) {
+				mu.Lock()
+				characters = append(characters, listedCharacter{character: character, category: folder.category})
+				mu.Unlock()
+			}
+		}(folder)
+	}
 
-		categoryName := dirent.Name()
-		categoryPath := filepath.Join(h.config.CharactersRootPath, categoryName)
-		mu.Lock()
-		response.Categories[categoryName] = make([]models.Character, 0)
-		mu.Unlock()
+	wg.Wait()
+	return scan, characters, nil
+}
+
+// GetIntegrityReport 检查整个角色库的文件完整性，列出所有损坏的文件
+func (h *CardsHandler) GetIntegrityReport(w http.ResponseWriter, r *http.Request) {
+	defer h.cacheManager.Save()
 
-		itemDirents, err := os.ReadDir(categoryPath)
+	cardsData, err := h.fetchCardsData()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "无法获取卡片数据", err)
+		return
+	}
+
+	report := models.IntegrityReport{BrokenFiles: make([]models.BrokenFile, 0)}
+	for categoryName, characters := range cardsData.Categories {
+		for _, character := range characters {
+			for _, version := range character.Versions {
+				report.CheckedFiles++
+				if len(version.Problems) > 0 {
+					report.BrokenFiles = append(report.BrokenFiles, models.BrokenFile{
+						Path:      version.Path,
+						FileName:  version.FileName,
+						Category:  categoryName,
+						Character: character.Name,
+						Problems:  version.Problems,
+					})
+				}
+			}
+		}
+	}
+	for _, stray := range cardsData.StrayCards {
+		report.CheckedFiles++
+		metadata, err := h.getCardMetadata(stray.Path)
 		if err != nil {
-			slog.Warn("📂 无法读取分类目录", "路径", categoryPath, "error", err)
 			continue
 		}
-
-		for _, item := range itemDirents {
-			itemPath := filepath.Join(categoryPath, item.Name())
-			if item.IsDir() {
-				wg.Add(1)
-				go func(itemPath, categoryName string) {
-					defer wg.Done()
-					character := h.processCharacterDirectory(itemPath)
-					if character != nil {
-						mu.Lock()
-						response.Categories[categoryName] = append(response.Categories[categoryName], *character)
-						mu.Unlock()
-					}
-				}(itemPath, categoryName)
-			} else if strings.HasSuffix(strings.ToLower(item.Name()), ".png") {
-				mu.Lock()
-				response.StrayCards = append(response.StrayCards, models.StrayCard{
-					FileName: item.Name(),
-					Path:     itemPath,
-				})
-				mu.Unlock()
-			}
+		if len(metadata.Problems) > 0 {
+			report.BrokenFiles = append(report.BrokenFiles, models.BrokenFile{
+				Path:     stray.Path,
+				FileName: stray.FileName,
+				Problems: metadata.Problems,
+			})
 		}
 	}
 
-	wg.Wait()
-	return response, nil
+	sort.Slice(report.BrokenFiles, func(i, j int) bool {
+		return report.BrokenFiles[i].Path < report.BrokenFiles[j].Path
+	})
+
+	slog.Info("🩺 完整性检查完成", "文件数", report.CheckedFiles, "损坏", len(report.BrokenFiles))
+	writeSuccessResponse(w, fmt.Sprintf("检查了 %d 个文件，发现 %d 个损坏文件", report.CheckedFiles, len(report.BrokenFiles)), report)
 }
 
 // processCharacterDirectory 处理单个角色目录
@@ -176,20 +273,15 @@ func (h *CardsHandler) processCharacterDirectory(itemPath string) *models.Charac
 	faceDirPath := filepath.Join(itemPath, "卡面")
 	if _, err := os.Stat(faceDirPath); err == nil {
 		hasFaceFolder = true
+		h.hashFaces(faceDirPath)
 	}
 
 	for _, verFile := range versionFiles {
-		if !verFile.IsDir() && strings.HasSuffix(strings.ToLower(verFile.Name()), ".png") {
-			verPath := filepath.Join(itemPath, verFile.Name())
-			metadata, _ := h.getCardMetadata(verPath)
-			versions = append(versions, models.CardVersion{
-				Path:         verPath,
-				FileName:     verFile.Name(),
-				Mtime:        metadata.Mtime,
-				InternalName: metadata.InternalName,
-			})
+		if !verFile.IsDir() && cardfile.IsCardFile(verFile.Name()) {
+			versions = append(versions, h.cardVersion(filepath.Join(itemPath, verFile.Name())))
 		} else if !verFile.IsDir() && strings.ToLower(verFile.Name()) == "note.md" {
 			hasNote = true
+			h.indexNote(filepath.Join(itemPath, verFile.Name()))
 		}
 	}
 
@@ -202,6 +294,13 @@ func (h *CardsHandler) processCharacterDirectory(itemPath string) *models.Charac
 		t2, _ := time.Parse(time.RFC3339Nano, versions[j].Mtime)
 		return t1.After(t2)
 	})
+	notes, err := sidecar.Load(itemPath)
+	if err != nil {
+		slog.Warn("读取角色附属数据失败", "路径", itemPath, "error", err)
+	}
+	current, pinned := h.applySidecar(notes, versions)
+	versions[current].IsCurrent = true
+	currentVersion := versions[current]
 
 	// 处理导入信息和本地化状态
 	importInfo := models.ImportInfo{}
@@ -219,34 +318,40 @@ func (h *CardsHandler) processCharacterDirectory(itemPath string) *models.Charac
 				isImported = true
 			}
 
-			if isImported {
+			if !isImported {
+				continue
+			}
+			// 记录最新导入的版本，但当前版本也已导入时以当前版本为准
+			if !importInfo.IsImported || i == current {
 				importInfo.IsImported = true
 				importInfo.ImportedVersionPath = version.Path
-				importInfo.IsLatestImported = i == 0
+				importInfo.IsLatestImported = i == current
+			}
+			if i >= current {
 				break
 			}
 		}
 	}
 	
-	metadata, _ := h.getCardMetadata(versions[0].Path)
+	metadata, _ := h.getCardMetadata(currentVersion.Path)
 	var localizationNeeded *bool
 	if metadata.LocalizationNeeded != nil {
 		localizationNeeded = metadata.LocalizationNeeded
 	} else {
 		// 如果缓存中没有本地化状态，进行检查
-		needed, err := h.checkLocalizationNeeded(versions[0].Path)
+		needed, err := h.checkLocalizationNeeded(currentVersion.Path)
 		if err != nil {
-			slog.Warn("检查本地化状态失败", "path", versions[0].Path, "error", err)
+			slog.Warn("检查本地化状态失败", "path", currentVersion.Path, "error", err)
 			// 如果检查失败，设置为不需要本地化
 			needed = false
 		}
 		localizationNeeded = &needed
 		// 更新缓存
 		metadata.LocalizationNeeded = localizationNeeded
-		h.cacheManager.Set(versions[0].Path, metadata)
+		h.cacheManager.Set(currentVersion.Path, metadata)
 	}
 
-	nameToCheck := versions[0].InternalName
+	nameToCheck := currentVersion.InternalName
 	if nameToCheck == "" {
 		nameToCheck = characterName
 	}
@@ -258,12 +363,23 @@ func (h *CardsHandler) processCharacterDirectory(itemPath string) *models.Charac
 		slog.Warn("检查本地化完成状态失败", "character", nameToCheck, "error", err)
 		isLocalized = false
 	}
+	// 改名前已本地化的角色，资源仍在原来的名称下
+	if !isLocalized {
+		if notes.LocalizedName != "" {
+			isLocalized, _ = localizationService.IsLocalized(notes.LocalizedName)
+		}
+	}
+
+	registry, err := h.tags.Load()
+	if err != nil {
+		slog.Warn("读取标签登记表失败", "error", err)
+	}
 
 	return &models.Character{
 		Name:               characterName,
-		InternalName:       versions[0].InternalName,
+		InternalName:       currentVersion.InternalName,
 		FolderPath:         itemPath,
-		LatestVersionPath:  versions[0].Path,
+		LatestVersionPath:  currentVersion.Path,
 		VersionCount:       len(versions),
 		Versions:           versions,
 		HasNote:            hasNote,
@@ -271,7 +387,52 @@ func (h *CardsHandler) processCharacterDirectory(itemPath string) *models.Charac
 		ImportInfo:         importInfo,
 		LocalizationNeeded: localizationNeeded,
 		IsLocalized:        isLocalized,
+		CurrentPinned:      pinned,
+		Creator:            metadata.Creator,
+		Tags:               tags.Merge(registry.Resolve(metadata.Tags), notes.Tags),
+		UserTags:           tags.Merge(notes.Tags),
+		Favorite:           notes.Favorite,
+		Rating:             notes.Rating,
+		Status:             characterStatus(notes),
+	}
+}
+
+// cardVersion 根据版本文件的元数据生成版本信息
+func (h *CardsHandler) cardVersion(verPath string) models.CardVersion {
+	metadata, _ := h.getCardMetadata(verPath)
+	return models.CardVersion{
+		Path:            verPath,
+		FileName:        filepath.Base(verPath),
+		Mtime:           metadata.Mtime,
+		InternalName:    metadata.InternalName,
+		Problems:        metadata.Problems,
+		LorebookEntries: metadata.LorebookEntries,
+	}
+}
+
+// applySidecar 从角色文件夹的附属数据中填充版本的标签和备注，并返回当前版本的下标
+//
+// 未手动指定当前版本时以修改时间最新的版本（下标 0）为准，第二个返回值表示是否为手动指定。
+func (h *CardsHandler) applySidecar(notes *sidecar.Sidecar, versions []models.CardVersion) (int, bool) {
+	if len(notes.Versions) == 0 && notes.CurrentVersion == nil {
+		return 0, false
 	}
+
+	keys := make([]sidecar.VersionKey, len(versions))
+	for i, version := range versions {
+		metadata, _ := h.cacheManager.Get(version.Path)
+		keys[i] = sidecar.VersionKey{Hash: metadata.Hash, FileName: version.FileName}
+	}
+	for i, note := range notes.MatchVersions(keys) {
+		if note != nil {
+			versions[i].Label = note.Label
+			versions[i].Comment = note.Comment
+		}
+	}
+	if current := notes.CurrentIndex(keys); current >= 0 {
+		return current, true
+	}
+	return 0, false
 }
 
 // getCardMetadata 获取卡片元数据
@@ -283,63 +444,61 @@ func (h *CardsHandler) getCardMetadata(filePath string) (cache.Entry, error) {
 	mtime := stats.ModTime().Format(time.RFC3339Nano)
 
 	cachedData, found := h.cacheManager.Get(filePath)
-	if found && cachedData.Mtime == mtime {
+	if found && cachedData.Mtime == mtime && cachedData.Schema >= metadataSchema {
+		if !h.index.Has(filePath, mtime) {
+			h.indexCardFile(filePath, mtime)
+		}
 		return cachedData, nil
 	}
 
-	hash, err := h.getFileHash(filePath)
+	info, err := cardfile.Inspect(filePath)
 	if err != nil {
 		return cache.Entry{Mtime: mtime}, err
 	}
-
-	var internalName string
-	charaData, err := h.getInternalCharNameFromPNG(filePath)
-	if err == nil {
-		decoded, err := base64.StdEncoding.DecodeString(charaData)
-		if err == nil {
-			var charDataJSON map[string]interface{}
-			if json.Unmarshal(decoded, &charDataJSON) == nil {
-				if name, ok := charDataJSON["name"].(string); ok && name != "" {
-					internalName = name
-				} else if name, ok := charDataJSON["char_name"].(string); ok && name != "" {
-					internalName = name
-				}
-			}
+	var internalName, creator string
+	var lorebookEntries int
+	var cardTags []string
+	if info.Card != nil {
+		internalName = info.Card.Name()
+		creator = info.Card.Data.Creator
+		cardTags = info.Card.Data.Tags
+		if book := info.Card.Data.CharacterBook; book != nil {
+			lorebookEntries = len(book.Entries)
 		}
 	}
+	h.index.Update(filePath, mtime, cardFields(info.Card))
+	if len(info.Problems) > 0 {
+		slog.Warn("⚠️ 角色卡文件已损坏", "文件", filepath.Base(filePath), "问题", info.Problems)
+	}
 
 	metadata := cache.Entry{
-		Hash:         hash,
-		InternalName: internalName,
-		Mtime:        mtime,
+		Hash:            info.Hash,
+		InternalName:    internalName,
+		Mtime:           mtime,
+		Problems:        info.Problems,
+		LorebookEntries: lorebookEntries,
+		Creator:         creator,
+		Tags:            cardTags,
+		Schema:          metadataSchema,
 	}
-
-	h.cacheManager.Set(filePath, metadata)
-	return metadata, nil
-}
-
-// getFileHash 计算文件的SHA256哈希
-func (h *CardsHandler) getFileHash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", err
+	if found && cachedData.Mtime == mtime {
+		metadata.LocalizationNeeded = cachedData.LocalizationNeeded
+		metadata.ImageHash = cachedData.ImageHash
 	}
-	defer file.Close()
-
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
+	if metadata.ImageHash == "" {
+		metadata.ImageHash = avatarHash(filePath)
 	}
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
-}
 
-// getInternalCharNameFromPNG 从PNG文件中提取角色数据
-func (h *CardsHandler) getInternalCharNameFromPNG(filePath string) (string, error) {
-	return png.GetInternalCharNameFromPNG(filePath)
+	h.cacheManager.Set(filePath, metadata)
+	return metadata, nil
 }
 
 // checkLocalizationNeeded 检查是否需要本地化
 func (h *CardsHandler) checkLocalizationNeeded(cardPath string) (bool, error) {
+	// 本地化工具只处理 PNG 角色卡
+	if cardfile.IsCharx(cardPath) {
+		return false, nil
+	}
 	// 创建一个临时的本地化服务来检查
 	localizationService := localization.NewService(h.config.TavernPublicPath, h.config.Proxy)
 	return localizationService.CheckLocalizationNeeded(cardPath)
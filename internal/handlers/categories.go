@@ -0,0 +1,498 @@
+package handlers
+
+import (
+	"card-manager/internal/config"
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/cache"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/journal"
+	"card-manager/internal/pkg/sidecar"
+	"card-manager/internal/pkg/trash"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxCategoryDepth 子分类最多嵌套的层数
+const maxCategoryDepth = 8
+
+// 角色库中文件夹的类型
+const (
+	// folderCharacter 直接包含角色卡的角色文件夹
+	folderCharacter = iota
+	// folderCategory 不含角色卡的文件夹，作为子分类
+	folderCategory
+	// folderLeftover 版本已全部删除、只剩备注或卡面的角色文件夹
+	folderLeftover
+)
+
+// characterFolder 扫描到的角色文件夹及其所在分类
+type characterFolder struct {
+	path     string
+	category string
+}
+
+// libraryScan 角色库的目录结构，不读取角色卡内容
+type libraryScan struct {
+	tree       []models.CategoryNode
+	characters []characterFolder
+	strays     []models.StrayCard
+}
+
+// scanLibrary 扫描角色库的分类树，列出所有角色文件夹和待整理卡片
+//
+// 根目录下的每个文件夹都是分类；分类中直接包含角色卡的文件夹是角色，
+// 不含角色卡的文件夹是子分类。待整理卡片是直接放在顶层分类中的角色卡文件。
+func scanLibrary(rootPath string) (*libraryScan, error) {
+	rootDirents, err := os.ReadDir(rootPath)
+	if err != nil {
+		slog.Error("📂 无法读取角色根目录", "路径", rootPath, "error", err)
+		return nil, fmt.Errorf("无法读取角色根目录: %w", err)
+	}
+
+	scan := &libraryScan{
+		tree:       make([]models.CategoryNode, 0),
+		characters: make([]characterFolder, 0),
+		strays:     make([]models.StrayCard, 0),
+	}
+	for _, dirent := range rootDirents {
+		// 以点开头的目录存放角色库元数据和回收站，不是分类
+		if !dirent.IsDir() || strings.HasPrefix(dirent.Name(), ".") {
+			continue
+		}
+		categoryPath := filepath.Join(rootPath, dirent.Name())
+		scan.tree = append(scan.tree, scan.category(categoryPath, dirent.Name(), 1))
+	}
+	return scan, nil
+}
+
+// category 扫描一个分类目录，返回分类树节点
+func (s *libraryScan) category(folderPath, categoryPath string, depth int) models.CategoryNode {
+	node := models.CategoryNode{
+		Name:       filepath.Base(folderPath),
+		Path:       categoryPath,
+		FolderPath: folderPath,
+		Children:   make([]models.CategoryNode, 0),
+	}
+	itemDirents, err := os.ReadDir(folderPath)
+	if err != nil {
+		slog.Warn("📂 无法读取分类目录", "路径", folderPath, "error", err)
+		return node
+	}
+
+	for _, item := range itemDirents {
+		itemPath := filepath.Join(folderPath, item.Name())
+		if !item.IsDir() {
+			if cardfile.IsCardFile(item.Name()) {
+				s.strays = append(s.strays, models.StrayCard{FileName: item.Name(), Path: itemPath, Category: categoryPath})
+			}
+			continue
+		}
+		if strings.HasPrefix(item.Name(), ".") {
+			continue
+		}
+		switch classifyFolder(itemPath) {
+		case folderCharacter:
+			s.characters = append(s.characters, characterFolder{path: itemPath, category: categoryPath})
+			node.CharacterCount++
+		case folderCategory:
+			if depth < maxCategoryDepth {
+				node.Children = append(node.Children, s.category(itemPath, categoryPath+"/"+item.Name(), depth+1))
+			}
+		}
+	}
+
+	node.TotalCharacters = node.CharacterCount
+	for _, child := range node.Children {
+		node.TotalCharacters += child.TotalCharacters
+	}
+	return node
+}
+
+// categoryPaths 按深度优先的顺序列出树中所有分类的路径
+func categoryPaths(nodes []models.CategoryNode) []string {
+	paths := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		paths = append(paths, node.Path)
+		paths = append(paths, categoryPaths(node.Children)...)
+	}
+	return paths
+}
+
+// classifyFolder 根据文件夹的内容判断它是角色、子分类还是角色的残留文件夹
+func classifyFolder(folderPath string) int {
+	entries, err := os.ReadDir(folderPath)
+	if err != nil {
+		return folderLeftover
+	}
+	kind := folderCategory
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			if name == "卡面" {
+				kind = folderLeftover
+			}
+			continue
+		}
+		if cardfile.IsCardFile(name) {
+			return folderCharacter
+		}
+		if strings.ToLower(name) == "note.md" || sidecar.IsSidecar(name) {
+			kind = folderLeftover
+		}
+	}
+	return kind
+}
+
+// CategoriesHandler 处理分类管理相关的API请求
+type CategoriesHandler struct {
+	config       *config.Config
+	cacheManager *cache.Manager
+	trash        *trash.Bin
+	journal      *journal.Journal
+}
+
+// NewCategoriesHandler 创建新的分类处理器
+func NewCategoriesHandler(config *config.Config, cacheManager *cache.Manager, trashBin *trash.Bin, operations *journal.Journal) *CategoriesHandler {
+	return &CategoriesHandler{
+		config:       config,
+		cacheManager: cacheManager,
+		trash:        trashBin,
+		journal:      operations,
+	}
+}
+
+// ListCategories 获取分类树，只统计角色数，不读取角色卡内容
+func (h *CategoriesHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	scan, err := scanLibrary(h.config.CharactersRootPath)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "获取分类失败", err)
+		return
+	}
+	writeSuccessResponse(w, fmt.Sprintf("共 %d 个顶层分类", len(scan.tree)), scan.tree)
+}
+
+// CreateCategory 新建分类，路径中缺少的上级分类一并创建
+func (h *CategoriesHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.CategoryRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	categoryPath, reason := normalizeCategoryPath(req.Path)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	folderPath := h.categoryFolder(categoryPath)
+	if _, err := os.Stat(folderPath); err == nil {
+		writeErrorResponseWithData(w, http.StatusConflict, "已存在同名的文件夹: "+categoryPath, nil, map[string]string{
+			"conflictPath": folderPath,
+		})
+		return
+	}
+	if reason := checkCategoryFolder(h.config.CharactersRootPath, folderPath); reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+
+	steps, err := makeDirs(folderPath)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "创建分类失败", err)
+		return
+	}
+	h.journal.Record(opCreateCategory, "新建分类 "+categoryPath, steps...)
+
+	slog.Info("🗂️ 分类已创建", "分类", categoryPath)
+	writeSuccessResponse(w, "分类已创建: "+categoryPath, map[string]string{
+		"path":       categoryPath,
+		"folderPath": folderPath,
+	})
+}
+
+// RenameCategory 重命名分类，新路径位于其他分类下时即为移动分类
+func (h *CategoriesHandler) RenameCategory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.RenameCategoryRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	oldPath, folderPath, ok := h.existingCategory(w, req.Path)
+	if !ok {
+		return
+	}
+	newPath, reason := normalizeCategoryPath(req.NewPath)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	if newPath == oldPath {
+		writeErrorResponse(w, http.StatusBadRequest, "新名称与原名称相同", nil)
+		return
+	}
+	if strings.HasPrefix(newPath, oldPath+"/") {
+		writeErrorResponse(w, http.StatusBadRequest, "不能把分类移动到它自己的子分类中", nil)
+		return
+	}
+	newFolderPath := h.categoryFolder(newPath)
+	if existing, err := os.Stat(newFolderPath); err == nil {
+		// 只改大小写时在不区分大小写的文件系统上会找到自身
+		if info, statErr := os.Stat(folderPath); statErr != nil || !os.SameFile(info, existing) {
+			writeErrorResponseWithData(w, http.StatusConflict, "已存在同名的文件夹: "+newPath, nil, map[string]string{
+				"conflictPath": newFolderPath,
+			})
+			return
+		}
+	}
+	if reason := checkCategoryFolder(h.config.CharactersRootPath, newFolderPath); reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	if strings.Contains(newPath, "/") && hasCardFiles(folderPath) {
+		writeErrorResponse(w, http.StatusBadRequest, "分类中有待整理卡片，整理后才能移动为子分类", nil)
+		return
+	}
+	defer h.cacheManager.Save()
+
+	steps, err := makeDirs(filepath.Dir(newFolderPath))
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "创建上级分类失败", err)
+		return
+	}
+	if err := os.Rename(folderPath, newFolderPath); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "重命名分类失败", err)
+		return
+	}
+	rekeyed := h.cacheManager.Rekey(folderPath, newFolderPath)
+	steps = append(steps, journal.Moved(folderPath, newFolderPath))
+	h.journal.Record(opRenameCategory, fmt.Sprintf("重命名分类 %s 为 %s", oldPath, newPath), steps...)
+
+	slog.Info("🗂️ 分类已重命名", "原分类", oldPath, "新分类", newPath, "缓存条目", rekeyed)
+	writeSuccessResponse(w, fmt.Sprintf("分类 %s 已重命名为 %s", oldPath, newPath), map[string]string{
+		"path":       newPath,
+		"folderPath": newFolderPath,
+	})
+}
+
+// MergeCategories 将来源分类中的角色、子分类和待整理卡片全部移入目标分类，然后删除来源分类
+//
+// 与目标分类中已有的项目重名时不移动，留在来源分类中并在结果中列出。
+func (h *CategoriesHandler) MergeCategories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.MergeCategoriesRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	sourcePath, sourceFolder, ok := h.existingCategory(w, req.Source)
+	if !ok {
+		return
+	}
+	targetPath, targetFolder, ok := h.existingCategory(w, req.Target)
+	if !ok {
+		return
+	}
+	if sourcePath == targetPath || strings.HasPrefix(targetPath, sourcePath+"/") || strings.HasPrefix(sourcePath, targetPath+"/") {
+		writeErrorResponse(w, http.StatusBadRequest, "不能合并相同或互相包含的分类", nil)
+		return
+	}
+
+	defer h.cacheManager.Save()
+
+	result := &mergeResult{moved: make([]string, 0), conflicts: make([]string, 0)}
+	h.mergeFolder(sourceFolder, targetFolder, sourcePath, strings.Contains(targetPath, "/"), result)
+	_, statErr := os.Stat(sourceFolder)
+	removed := os.IsNotExist(statErr)
+	if len(result.steps) > 0 {
+		h.journal.Record(opMergeCategories, fmt.Sprintf("合并分类 %s 到 %s", sourcePath, targetPath), result.steps...)
+	}
+
+	slog.Info("🗂️ 分类已合并", "来源", sourcePath, "目标", targetPath, "移动", len(result.moved), "冲突", len(result.conflicts))
+	data := map[string]interface{}{"moved": result.moved, "conflicts": result.conflicts, "sourceRemoved": removed}
+	if len(result.conflicts) > 0 {
+		writeErrorResponseWithData(w, http.StatusConflict, fmt.Sprintf("已移动 %d 项，%d 项与目标分类中的项目重名或是不能放入子分类的待整理卡片，仍留在原处", len(result.moved), len(result.conflicts)), nil, data)
+		return
+	}
+	writeSuccessResponse(w, fmt.Sprintf("分类 %s 已合并到 %s，共移动 %d 项", sourcePath, targetPath, len(result.moved)), data)
+}
+
+// mergeResult 合并分类的结果，moved 和 conflicts 为相对来源分类的路径
+type mergeResult struct {
+	moved     []string
+	conflicts []string
+	steps     []journal.Step
+}
+
+// mergeFolder 将 src 中的项目移入 dst，两边都有的同名子分类递归合并，src 清空后移入回收站
+//
+// nested 表示 dst 是子分类，子分类中出现角色卡文件会让它变成角色文件夹，所以待整理卡片不能移入。
+func (h *CategoriesHandler) mergeFolder(src, dst, relPath string, nested bool, result *mergeResult) {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		slog.Warn("读取来源分类失败", "路径", src, "error", err)
+		result.conflicts = append(result.conflicts, relPath)
+		return
+	}
+	conflicts := len(result.conflicts)
+	for _, entry := range entries {
+		srcItem := filepath.Join(src, entry.Name())
+		dstItem := filepath.Join(dst, entry.Name())
+		itemPath := relPath + "/" + entry.Name()
+		if _, err := os.Stat(dstItem); err == nil {
+			if entry.IsDir() && classifyFolder(srcItem) == folderCategory && classifyFolder(dstItem) == folderCategory {
+				h.mergeFolder(srcItem, dstItem, itemPath, true, result)
+			} else {
+				result.conflicts = append(result.conflicts, itemPath)
+			}
+			continue
+		}
+		if nested && !entry.IsDir() && cardfile.IsCardFile(entry.Name()) {
+			result.conflicts = append(result.conflicts, itemPath)
+			continue
+		}
+		if err := os.Rename(srcItem, dstItem); err != nil {
+			slog.Warn("合并分类时移动失败", "路径", srcItem, "error", err)
+			result.conflicts = append(result.conflicts, itemPath)
+			continue
+		}
+		h.cacheManager.Rekey(srcItem, dstItem)
+		result.steps = append(result.steps, journal.Moved(srcItem, dstItem))
+		result.moved = append(result.moved, itemPath)
+	}
+
+	if len(result.conflicts) > conflicts {
+		return
+	}
+	if item, err := h.trash.Trash(src, trash.KindCategory); err != nil {
+		slog.Warn("删除已合并的分类失败", "路径", src, "error", err)
+	} else {
+		result.steps = append(result.steps, journal.Trashed(src, item.ID))
+	}
+}
+
+// DeleteCategory 删除空分类，只含空的子分类也算空，文件夹移入回收站
+func (h *CategoriesHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.CategoryRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	categoryPath, folderPath, ok := h.existingCategory(w, req.Path)
+	if !ok {
+		return
+	}
+	if file := firstFile(folderPath); file != "" {
+		writeErrorResponseWithData(w, http.StatusConflict, "分类不为空，只能删除空分类", nil, map[string]string{
+			"file": file,
+		})
+		return
+	}
+
+	item, err := h.trash.Trash(folderPath, trash.KindCategory)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "删除分类失败", err)
+		return
+	}
+	h.journal.Record(opDeleteCategory, "删除分类 "+categoryPath, journal.Trashed(folderPath, item.ID))
+
+	slog.Info("🗂️ 分类已删除", "分类", categoryPath)
+	writeSuccessResponse(w, "分类已删除: "+categoryPath, nil)
+}
+
+// existingCategory 解析请求中的分类路径并确认它是已存在的分类，失败时直接写出错误响应
+func (h *CategoriesHandler) existingCategory(w http.ResponseWriter, path string) (string, string, bool) {
+	categoryPath, reason := normalizeCategoryPath(path)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return "", "", false
+	}
+	folderPath := h.categoryFolder(categoryPath)
+	if info, err := os.Stat(folderPath); err != nil || !info.IsDir() {
+		writeErrorResponse(w, http.StatusNotFound, "分类不存在: "+categoryPath, err)
+		return "", "", false
+	}
+	// 顶层文件夹总是分类，子文件夹中有角色卡时是角色
+	if strings.Contains(categoryPath, "/") && classifyFolder(folderPath) != folderCategory {
+		writeErrorResponse(w, http.StatusBadRequest, categoryPath+" 是角色文件夹，不是分类", nil)
+		return "", "", false
+	}
+	return categoryPath, folderPath, true
+}
+
+// checkCategoryFolder 检查文件夹及其上级都不是角色文件夹，返回不能用作分类的原因
+func checkCategoryFolder(rootPath, folderPath string) string {
+	rootPath = filepath.Clean(rootPath)
+	for dir := filepath.Clean(folderPath); folderDepth(rootPath, dir) >= 2; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil && classifyFolder(dir) != folderCategory {
+			return filepath.Base(dir) + " 是角色文件夹，不能用作分类"
+		}
+	}
+	return ""
+}
+
+// categoryFolder 分类路径对应的文件夹
+func (h *CategoriesHandler) categoryFolder(categoryPath string) string {
+	return filepath.Join(h.config.CharactersRootPath, filepath.FromSlash(categoryPath))
+}
+
+// normalizeCategoryPath 整理以 / 或 \ 分隔的分类路径，返回以 / 分隔的路径和不能使用的原因
+func normalizeCategoryPath(path string) (string, string) {
+	path = strings.Trim(strings.ReplaceAll(path, "\\", "/"), "/")
+	if path == "" {
+		return "", "缺少分类名称"
+	}
+	segments := strings.Split(path, "/")
+	if len(segments) > maxCategoryDepth {
+		return "", fmt.Sprintf("分类最多嵌套 %d 层", maxCategoryDepth)
+	}
+	for i, segment := range segments {
+		segments[i] = strings.TrimSpace(segment)
+		if reason := validateFolderName(segments[i]); reason != "" {
+			return "", fmt.Sprintf("分类名称「%s」无效: %s", segment, reason)
+		}
+	}
+	return strings.Join(segments, "/"), ""
+}
+
+// hasCardFiles 判断文件夹中是否直接包含角色卡文件
+func hasCardFiles(folderPath string) bool {
+	return classifyFolder(folderPath) == folderCharacter
+}
+
+// firstFile 返回文件夹中找到的第一个文件，没有文件时返回空串，以点开头的系统文件不计
+func firstFile(folderPath string) string {
+	errFound := errors.New("found")
+	found := ""
+	filepath.WalkDir(folderPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		found = path
+		return errFound
+	})
+	return found
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/collections"
+	"card-manager/internal/pkg/journal"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ListCollections 列出分类树和智能收藏夹，收藏夹的角色数按当前的角色库重新统计
+func (h *CardsHandler) ListCollections(w http.ResponseWriter, r *http.Request) {
+	defer h.cacheManager.Save()
+	defer h.index.Save()
+
+	list, err := h.collections.Load()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "读取智能收藏夹失败", err)
+		return
+	}
+	scan, characters, err := h.collectCharacters(nil)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "获取角色列表失败", err)
+		return
+	}
+
+	response := models.CollectionListResponse{
+		Categories:  scan.tree,
+		Collections: make([]models.CollectionInfo, 0, len(list)),
+	}
+	for _, collection := range list {
+		info := models.CollectionInfo{Name: collection.Name, Query: collection.Query}
+		// 收藏夹文件可能被手动修改过，条件无效时照常列出，方便用户修改或删除
+		_, filter, err := parseCollectionQuery(collection.Query)
+		if err != nil {
+			info.Error = err.(*models.AppError).Message
+		} else {
+			for _, listed := range characters {
+				if filter.matchCategory(listed.category) && filter.matchCharacter(listed.character) {
+					info.Count++
+				}
+			}
+		}
+		response.Collections = append(response.Collections, info)
+	}
+	writeSuccessResponse(w, fmt.Sprintf("共 %d 个智能收藏夹", len(response.Collections)), response)
+}
+
+// SaveCollection 保存智能收藏夹，同名的收藏夹（不区分大小写）替换为新的筛选条件
+func (h *CardsHandler) SaveCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.CollectionRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	name, reason := collections.NormalizeName(req.Name)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	query, filter, err := parseCollectionQuery(req.Query)
+	if err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	defer h.cacheManager.Save()
+	defer h.index.Save()
+
+	created := false
+	step, err := h.updateCollections(func(list []collections.Collection) ([]collections.Collection, error) {
+		collection := collections.Collection{Name: name, Query: query}
+		if i := collections.Index(list, name); i >= 0 {
+			list[i] = collection
+		} else {
+			list = append(list, collection)
+			created = true
+		}
+		return list, nil
+	})
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "保存智能收藏夹失败", err)
+		return
+	}
+	h.journal.Record(opSaveCollection, "保存智能收藏夹 "+name, step)
+
+	info := models.CollectionInfo{Name: name, Query: query}
+	if _, characters, err := h.collectCharacters(filter); err == nil {
+		info.Count = len(characters)
+	}
+	slog.Info("🔖 智能收藏夹已保存", "名称", name, "条件", query, "新建", created)
+	writeSuccessResponse(w, "智能收藏夹已保存: "+name, info)
+}
+
+// RenameCollection 重命名智能收藏夹
+func (h *CardsHandler) RenameCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.RenameCollectionRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	name, reason := collections.NormalizeName(req.Name)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	newName, reason := collections.NormalizeName(req.NewName)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	if newName == name {
+		writeErrorResponse(w, http.StatusBadRequest, "新名称与原名称相同", nil)
+		return
+	}
+
+	step, err := h.updateCollections(func(list []collections.Collection) ([]collections.Collection, error) {
+		i := collections.Index(list, name)
+		if i < 0 {
+			return nil, collections.ErrNotFound
+		}
+		// 只改大小写时新名称就是它自己
+		if j := collections.Index(list, newName); j >= 0 && j != i {
+			return nil, collections.ErrExists
+		}
+		list[i].Name = newName
+		return list, nil
+	})
+	if !collectionUpdated(w, err, name, newName, "重命名智能收藏夹失败") {
+		return
+	}
+	h.journal.Record(opRenameCollection, fmt.Sprintf("重命名智能收藏夹 %s 为 %s", name, newName), step)
+	slog.Info("🔖 智能收藏夹已重命名", "原名称", name, "新名称", newName)
+	writeSuccessResponse(w, fmt.Sprintf("智能收藏夹 %s 已重命名为 %s", name, newName), map[string]string{"name": newName})
+}
+
+// DeleteCollection 删除智能收藏夹，不影响其中的角色
+func (h *CardsHandler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.CollectionRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	name, reason := collections.NormalizeName(req.Name)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+
+	step, err := h.updateCollections(func(list []collections.Collection) ([]collections.Collection, error) {
+		i := collections.Index(list, name)
+		if i < 0 {
+			return nil, collections.ErrNotFound
+		}
+		return append(list[:i], list[i+1:]...), nil
+	})
+	if !collectionUpdated(w, err, name, "", "删除智能收藏夹失败") {
+		return
+	}
+	h.journal.Record(opDeleteCollection, "删除智能收藏夹 "+name, step)
+	slog.Info("🔖 智能收藏夹已删除", "名称", name)
+	writeSuccessResponse(w, "智能收藏夹已删除: "+name, nil)
+}
+
+// ExportCollections 以附件形式导出所有智能收藏夹，导出的文件可直接用于导入
+func (h *CardsHandler) ExportCollections(w http.ResponseWriter, r *http.Request) {
+	list, err := h.collections.Load()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "读取智能收藏夹失败", err)
+		return
+	}
+	data, err := json.MarshalIndent(collections.File{Collections: list}, "", "  ")
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "导出智能收藏夹失败", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": "collections.json",
+	}))
+	w.Write(data)
+}
+
+// ImportCollections 导入智能收藏夹，所有条目都有效时才写入
+func (h *CardsHandler) ImportCollections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.ImportCollectionsRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	imported := make([]collections.Collection, 0, len(req.Collections))
+	for i, item := range req.Collections {
+		name, reason := collections.NormalizeName(item.Name)
+		if reason != "" {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("第 %d 个收藏夹: %s", i+1, reason), nil)
+			return
+		}
+		query, _, err := parseCollectionQuery(item.Query)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("收藏夹 %s: %s", name, err.(*models.AppError).Message), nil)
+			return
+		}
+		imported = append(imported, collections.Collection{Name: name, Query: query})
+	}
+
+	total := 0
+	step, err := h.updateCollections(func(list []collections.Collection) ([]collections.Collection, error) {
+		if req.Replace {
+			list = nil
+		}
+		for _, collection := range imported {
+			if i := collections.Index(list, collection.Name); i >= 0 {
+				list[i] = collection
+			} else {
+				list = append(list, collection)
+			}
+		}
+		total = len(list)
+		return list, nil
+	})
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "导入智能收藏夹失败", err)
+		return
+	}
+	h.journal.Record(opImportCollections, fmt.Sprintf("导入 %d 个智能收藏夹", len(imported)), step)
+	slog.Info("🔖 智能收藏夹已导入", "导入", len(imported), "共", total, "替换", req.Replace)
+	writeSuccessResponse(w, fmt.Sprintf("已导入 %d 个智能收藏夹", len(imported)), map[string]int{
+		"imported": len(imported),
+		"total":    total,
+	})
+}
+
+// updateCollections 修改收藏夹列表，返回撤销修改的步骤
+func (h *CardsHandler) updateCollections(modify func(list []collections.Collection) ([]collections.Collection, error)) (journal.Step, error) {
+	return journaledWrite(h.trash, h.collections.Path(), func() error {
+		return h.collections.Update(modify)
+	})
+}
+
+// collectionUpdated 处理修改收藏夹列表的错误并写出错误响应，没有错误时返回 true
+func collectionUpdated(w http.ResponseWriter, err error, name, newName, message string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, collections.ErrNotFound):
+		writeErrorResponse(w, http.StatusNotFound, "智能收藏夹不存在: "+name, nil)
+	case errors.Is(err, collections.ErrExists):
+		writeErrorResponse(w, http.StatusConflict, "智能收藏夹已存在: "+newName, nil)
+	default:
+		writeErrorResponse(w, http.StatusInternalServerError, message, err)
+	}
+	return false
+}
+
+// requestFilter 解析请求的筛选条件，collection 参数指定的智能收藏夹条件与其他参数合并，同名参数以请求为准
+func (h *CardsHandler) requestFilter(query url.Values) (*cardFilter, error) {
+	name := query.Get("collection")
+	if name == "" {
+		return parseCardFilter(query)
+	}
+	list, err := h.collections.Load()
+	if err != nil {
+		return nil, models.NewInternalError("读取智能收藏夹失败", err)
+	}
+	i := collections.Index(list, name)
+	if i < 0 {
+		return nil, models.NewNotFoundError("智能收藏夹不存在: "+name, nil)
+	}
+	merged, err := url.ParseQuery(list[i].Query)
+	if err != nil {
+		return nil, models.NewBadRequestError("智能收藏夹的筛选条件无效: "+list[i].Name, err)
+	}
+	for key, values := range query {
+		if key != "collection" {
+			merged[key] = values
+		}
+	}
+	return parseCardFilter(merged)
+}
+
+// parseCollectionQuery 检查收藏夹的筛选条件，返回整理后的查询字符串；只能使用筛选参数，且不能为空
+func parseCollectionQuery(raw string) (string, *cardFilter, error) {
+	values, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(raw), "?"))
+	if err != nil {
+		return "", nil, models.NewBadRequestError("筛选条件格式无效", err)
+	}
+	for key := range values {
+		if !isFilterParam(key) {
+			return "", nil, models.NewBadRequestError("不支持的筛选参数: "+key, nil)
+		}
+	}
+	filter, err := parseCardFilter(values)
+	if err != nil {
+		return "", nil, err
+	}
+	if filter == nil {
+		return "", nil, models.NewBadRequestError("筛选条件不能为空", nil)
+	}
+	return values.Encode(), filter, nil
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"bytes"
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/card"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/diff"
+	"card-manager/internal/pkg/imaging"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// DiffVersions 比较两个版本的角色卡数据和头像，返回逐字段的差异
+func (h *CardsHandler) DiffVersions(w http.ResponseWriter, r *http.Request) {
+	oldPath := r.URL.Query().Get("oldPath")
+	newPath := r.URL.Query().Get("newPath")
+	if oldPath == "" || newPath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少要比较的版本路径", nil)
+		return
+	}
+	rootPath := filepath.Clean(h.config.CharactersRootPath)
+	if !withinRoot(rootPath, oldPath) || !withinRoot(rootPath, newPath) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+
+	oldCard, ok := loadVersionCard(w, oldPath)
+	if !ok {
+		return
+	}
+	newCard, ok := loadVersionCard(w, newPath)
+	if !ok {
+		return
+	}
+
+	response := diffCards(oldCard, newCard)
+	response.OldPath = oldPath
+	response.NewPath = newPath
+	response.AvatarChanged = avatarChanged(oldPath, newPath)
+	response.Identical = len(response.Fields) == 0 &&
+		len(response.AlternateGreetings.Added) == 0 && len(response.AlternateGreetings.Removed) == 0 &&
+		len(response.Tags.Added) == 0 && len(response.Tags.Removed) == 0 &&
+		len(response.Lorebook.Added) == 0 && len(response.Lorebook.Removed) == 0 && len(response.Lorebook.Modified) == 0 &&
+		!response.ExtensionsChanged && !response.AvatarChanged
+
+	message := "两个版本的内容相同"
+	if response.AvatarChanged && len(response.Fields) == 0 {
+		message = "两个版本的头像不同"
+	} else if !response.Identical {
+		message = fmt.Sprintf("两个版本存在差异，其中 %d 个文本字段有变化", len(response.Fields))
+	}
+	writeSuccessResponse(w, message, response)
+}
+
+// diffCards 比较两张角色卡的数据
+func diffCards(oldCard, newCard *card.Card) models.VersionDiffResponse {
+	response := models.VersionDiffResponse{Fields: make([]models.FieldDiff, 0)}
+
+	shortFields := []struct {
+		name     string
+		old, new string
+	}{
+		{"spec", oldCard.Version().String(), newCard.Version().String()},
+		{"name", oldCard.Name(), newCard.Name()},
+		{"nickname", oldCard.Data.Nickname, newCard.Data.Nickname},
+		{"creator", oldCard.Data.Creator, newCard.Data.Creator},
+		{"character_version", oldCard.Data.CharacterVersion, newCard.Data.CharacterVersion},
+	}
+	for _, f := range shortFields {
+		if f.old != f.new {
+			oldValue, newValue := f.old, f.new
+			response.Fields = append(response.Fields, models.FieldDiff{Field: f.name, Old: &oldValue, New: &newValue})
+		}
+	}
+
+	longFields := []struct {
+		name     string
+		old, new string
+	}{
+		{"description", oldCard.Data.Description, newCard.Data.Description},
+		{"personality", oldCard.Data.Personality, newCard.Data.Personality},
+		{"scenario", oldCard.Data.Scenario, newCard.Data.Scenario},
+		{"first_mes", oldCard.Data.FirstMes, newCard.Data.FirstMes},
+		{"mes_example", oldCard.Data.MesExample, newCard.Data.MesExample},
+		{"creator_notes", oldCard.Data.CreatorNotes, newCard.Data.CreatorNotes},
+		{"system_prompt", oldCard.Data.SystemPrompt, newCard.Data.SystemPrompt},
+		{"post_history_instructions", oldCard.Data.PostHistoryInstructions, newCard.Data.PostHistoryInstructions},
+	}
+	for _, f := range longFields {
+		if f.old != f.new {
+			response.Fields = append(response.Fields, models.FieldDiff{Field: f.name, Lines: diffLines(f.old, f.new)})
+		}
+	}
+
+	response.AlternateGreetings = diffLists(oldCard.Data.AlternateGreetings, newCard.Data.AlternateGreetings)
+	response.Tags = diffLists(oldCard.Data.Tags, newCard.Data.Tags)
+	response.Lorebook = diffLorebooks(oldCard.Data.CharacterBook, newCard.Data.CharacterBook)
+	response.ExtensionsChanged = !jsonEqual(oldCard.Data.Extensions, newCard.Data.Extensions)
+	return response
+}
+
+// diffLines 生成逐行文本差异
+func diffLines(oldText, newText string) []models.DiffLine {
+	lines := diff.Lines(oldText, newText)
+	result := make([]models.DiffLine, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, models.DiffLine{Op: string(line.Op), Text: line.Text})
+	}
+	return result
+}
+
+// diffLists 比较两个字符串列表，重复的元素按出现次数计算
+func diffLists(oldList, newList []string) models.ListDiff {
+	result := models.ListDiff{Added: make([]string, 0), Removed: make([]string, 0)}
+	remaining := make(map[string]int, len(oldList))
+	for _, item := range oldList {
+		remaining[item]++
+	}
+	for _, item := range newList {
+		if remaining[item] > 0 {
+			remaining[item]--
+		} else {
+			result.Added = append(result.Added, item)
+		}
+	}
+	for _, item := range oldList {
+		if remaining[item] > 0 {
+			remaining[item]--
+			result.Removed = append(result.Removed, item)
+		}
+	}
+	return result
+}
+
+// diffLorebooks 比较两个内嵌世界书的条目
+//
+// 条目按 id 对应，没有 id 时按关键词和备注对应；对应不上的条目视为新增或删除。
+func diffLorebooks(oldBook, newBook *card.CharacterBook) models.LorebookDiff {
+	result := models.LorebookDiff{
+		Added:    make([]models.LorebookEntry, 0),
+		Removed:  make([]models.LorebookEntry, 0),
+		Modified: make([]models.LorebookEntryChange, 0),
+	}
+	var oldEntries, newEntries []card.BookEntry
+	if oldBook != nil {
+		oldEntries = oldBook.Entries
+	}
+	if newBook != nil {
+		newEntries = newBook.Entries
+	}
+
+	unmatched := make(map[string][]int)
+	for i, entry := range oldEntries {
+		key := lorebookEntryKey(entry)
+		unmatched[key] = append(unmatched[key], i)
+	}
+	matchedOld := make(map[int]bool, len(oldEntries))
+	for i, entry := range newEntries {
+		key := lorebookEntryKey(entry)
+		candidates := unmatched[key]
+		if len(candidates) == 0 {
+			result.Added = append(result.Added, lorebookEntry(i, entry))
+			continue
+		}
+		oldIndex := candidates[0]
+		unmatched[key] = candidates[1:]
+		matchedOld[oldIndex] = true
+
+		oldEntry := oldEntries[oldIndex]
+		if changes := lorebookEntryChanges(oldEntry, entry); len(changes) > 0 {
+			change := models.LorebookEntryChange{Entry: lorebookEntry(i, entry), Changes: changes}
+			if oldEntry.Content != entry.Content {
+				change.ContentLines = diffLines(oldEntry.Content, entry.Content)
+			}
+			result.Modified = append(result.Modified, change)
+		}
+	}
+	for i, entry := range oldEntries {
+		if !matchedOld[i] {
+			result.Removed = append(result.Removed, lorebookEntry(i, entry))
+		}
+	}
+	return result
+}
+
+// lorebookEntryKey 用于在两个版本之间对应世界书条目的标识
+func lorebookEntryKey(entry card.BookEntry) string {
+	if len(entry.ID) > 0 {
+		return "id:" + string(entry.ID)
+	}
+	return "keys:" + strings.Join(entry.Keys, "\x00") + "\x01" + entry.Comment
+}
+
+// lorebookEntryChanges 列出世界书条目中发生变化的字段
+func lorebookEntryChanges(oldEntry, newEntry card.BookEntry) []string {
+	checks := []struct {
+		name  string
+		equal bool
+	}{
+		{"keys", jsonEqual(oldEntry.Keys, newEntry.Keys)},
+		{"secondary_keys", jsonEqual(oldEntry.SecondaryKeys, newEntry.SecondaryKeys)},
+		{"content", oldEntry.Content == newEntry.Content},
+		{"comment", oldEntry.Comment == newEntry.Comment},
+		{"name", oldEntry.Name == newEntry.Name},
+		{"enabled", oldEntry.Enabled == newEntry.Enabled},
+		{"constant", jsonEqual(oldEntry.Constant, newEntry.Constant)},
+		{"selective", jsonEqual(oldEntry.Selective, newEntry.Selective)},
+		{"position", oldEntry.Position == newEntry.Position},
+		{"insertion_order", oldEntry.InsertionOrder == newEntry.InsertionOrder},
+		{"extensions", jsonEqual(oldEntry.Extensions, newEntry.Extensions)},
+	}
+	changes := make([]string, 0)
+	for _, check := range checks {
+		if !check.equal {
+			changes = append(changes, check.name)
+		}
+	}
+	return changes
+}
+
+// avatarChanged 判断两个版本的头像像素是否不同，无法解码时比较原始数据
+func avatarChanged(oldPath, newPath string) bool {
+	oldData, errOld := cardfile.Avatar(oldPath)
+	newData, errNew := cardfile.Avatar(newPath)
+	if errOld != nil || errNew != nil {
+		return (errOld == nil) != (errNew == nil)
+	}
+
+	oldImage, _, errOld := imaging.Decode(oldData)
+	newImage, _, errNew := imaging.Decode(newData)
+	if errOld != nil || errNew != nil {
+		return !bytes.Equal(oldData, newData)
+	}
+	return !imaging.SamePixels(oldImage, newImage)
+}
+
+// jsonEqual 按序列化结果比较两个值，map 的键顺序不影响结果，nil 与空数组、空对象视为相同
+func jsonEqual(a, b interface{}) bool {
+	rawA, errA := json.Marshal(a)
+	rawB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(normalizeEmpty(rawA), normalizeEmpty(rawB))
+}
+
+func normalizeEmpty(raw []byte) []byte {
+	switch string(raw) {
+	case "[]", "{}":
+		return []byte("null")
+	}
+	return raw
+}
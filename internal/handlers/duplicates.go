@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/journal"
+	"card-manager/internal/pkg/sidecar"
+	"card-manager/internal/pkg/similarity"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// descriptionSimilarityThreshold 描述相似度达到该值时视为疑似重复
+const descriptionSimilarityThreshold = 0.9
+
+// 重复分组的类型
+const (
+	duplicateIdentical   = "identical"
+	duplicateSameName    = "sameName"
+	duplicateDescription = "similarDescription"
+)
+
+// GetDuplicates 查找整个角色库中重复和疑似重复的版本
+//
+// 可选的 threshold 参数（0 到 1）调整描述相似度的阈值。
+func (h *CardsHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	defer h.cacheManager.Save()
+
+	threshold := descriptionSimilarityThreshold
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			writeErrorResponse(w, http.StatusBadRequest, "相似度阈值应在 0 到 1 之间", err)
+			return
+		}
+		threshold = parsed
+	}
+
+	cardsData, err := h.fetchCardsData()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "无法获取卡片数据", err)
+		return
+	}
+
+	items := h.duplicateItems(cardsData)
+	report := models.DuplicatesReport{Groups: make([]models.DuplicateGroup, 0)}
+	report.Groups = append(report.Groups, identicalGroups(items)...)
+	report.Groups = append(report.Groups, sameNameGroups(items)...)
+	report.Groups = append(report.Groups, h.similarDescriptionGroups(items, threshold)...)
+
+	slog.Info("🔍 重复检测完成", "文件数", len(items), "分组数", len(report.Groups))
+	writeSuccessResponse(w, fmt.Sprintf("找到 %d 组重复或疑似重复的版本", len(report.Groups)), report)
+}
+
+// duplicateItems 收集角色库中的所有版本和待整理卡片，按路径排序
+func (h *CardsHandler) duplicateItems(cardsData models.CardsResponse) []models.DuplicateItem {
+	items := make([]models.DuplicateItem, 0)
+	for categoryName, characters := range cardsData.Categories {
+		for _, character := range characters {
+			for _, version := range character.Versions {
+				metadata, _ := h.cacheManager.Get(version.Path)
+				items = append(items, models.DuplicateItem{
+					Path:         version.Path,
+					FileName:     version.FileName,
+					Category:     categoryName,
+					Character:    character.Name,
+					FolderPath:   character.FolderPath,
+					InternalName: version.InternalName,
+					Hash:         metadata.Hash,
+					Mtime:        version.Mtime,
+				})
+			}
+		}
+	}
+	for _, stray := range cardsData.StrayCards {
+		metadata, err := h.getCardMetadata(stray.Path)
+		if err != nil {
+			continue
+		}
+		items = append(items, models.DuplicateItem{
+			Path:         stray.Path,
+			FileName:     stray.FileName,
+			FolderPath:   filepath.Dir(stray.Path),
+			InternalName: metadata.InternalName,
+			Hash:         metadata.Hash,
+			Mtime:        metadata.Mtime,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+	return items
+}
+
+// cardDescription 返回版本的描述，直接取全文索引中按修改时间保存的原文，
+// 索引过期时才重新读取文件
+func (h *CardsHandler) cardDescription(filePath, mtime string) string {
+	if !h.index.Has(filePath, mtime) {
+		h.indexCardFile(filePath, mtime)
+	}
+	for _, field := range h.index.Fields(filePath) {
+		if field.Name == "description" {
+			return field.Text
+		}
+	}
+	return ""
+}
+
+// identicalGroups 按哈希分组完全相同的文件
+func identicalGroups(items []models.DuplicateItem) []models.DuplicateGroup {
+	byHash := make(map[string][]models.DuplicateItem)
+	for _, item := range items {
+		if item.Hash != "" {
+			byHash[item.Hash] = append(byHash[item.Hash], item)
+		}
+	}
+
+	groups := make([]models.DuplicateGroup, 0)
+	for hash, members := range byHash {
+		if len(members) > 1 {
+			groups = append(groups, models.DuplicateGroup{Kind: duplicateIdentical, Key: hash, Similarity: 1, Items: members})
+		}
+	}
+	sortGroups(groups)
+	return groups
+}
+
+// sameNameGroups 分组内部名称相同但位于不同角色文件夹的版本
+func sameNameGroups(items []models.DuplicateItem) []models.DuplicateGroup {
+	byName := make(map[string][]models.DuplicateItem)
+	for _, item := range items {
+		if item.InternalName != "" {
+			byName[item.InternalName] = append(byName[item.InternalName], item)
+		}
+	}
+
+	groups := make([]models.DuplicateGroup, 0)
+	for name, members := range byName {
+		if countFolders(members) > 1 {
+			groups = append(groups, models.DuplicateGroup{Kind: duplicateSameName, Key: name, Items: members})
+		}
+	}
+	sortGroups(groups)
+	return groups
+}
+
+// similarDescriptionGroups 分组描述几乎相同且位于不同角色文件夹的版本
+func (h *CardsHandler) similarDescriptionGroups(items []models.DuplicateItem, threshold float64) []models.DuplicateGroup {
+	// 先合并描述完全相同的版本，同一角色的多个版本通常只需计算一次签名
+	textIndex := make(map[string]int)
+	var texts []string
+	var textItems [][]int
+	for i, item := range items {
+		text := similarity.Normalize(h.cardDescription(item.Path, item.Mtime))
+		index, ok := textIndex[text]
+		if !ok {
+			index = len(texts)
+			textIndex[text] = index
+			texts = append(texts, text)
+			textItems = append(textItems, nil)
+		}
+		textItems[index] = append(textItems[index], i)
+	}
+
+	var sigs []similarity.Signature
+	var sigTexts []int
+	for i, text := range texts {
+		if sig, ok := similarity.TextSignature(text); ok {
+			sigs = append(sigs, sig)
+			sigTexts = append(sigTexts, i)
+		}
+	}
+
+	sets := newUnionFind(len(items))
+	for _, textIndex := range sigTexts {
+		members := textItems[textIndex]
+		for _, member := range members[1:] {
+			sets.union(members[0], member, 1)
+		}
+	}
+	for _, pair := range similarity.SimilarPairs(sigs, threshold) {
+		a, b := textItems[sigTexts[pair.I]][0], textItems[sigTexts[pair.J]][0]
+		sets.union(a, b, pair.Similarity)
+	}
+
+	components := make(map[int][]models.DuplicateItem)
+	for i := range items {
+		if sets.size(i) > 1 {
+			root := sets.find(i)
+			components[root] = append(components[root], items[i])
+		}
+	}
+
+	groups := make([]models.DuplicateGroup, 0)
+	for root, members := range components {
+		if countFolders(members) > 1 {
+			key := members[0].InternalName
+			if key == "" {
+				key = members[0].FileName
+			}
+			groups = append(groups, models.DuplicateGroup{
+				Kind:       duplicateDescription,
+				Key:        key,
+				Similarity: sets.similarity[root],
+				Items:      members,
+			})
+		}
+	}
+	sortGroups(groups)
+	return groups
+}
+
+// countFolders 统计分组涉及的角色文件夹数，每张待整理卡片单独计数
+func countFolders(items []models.DuplicateItem) int {
+	folders := make(map[string]bool)
+	for _, item := range items {
+		if item.Character == "" {
+			folders[item.Path] = true
+		} else {
+			folders[item.FolderPath] = true
+		}
+	}
+	return len(folders)
+}
+
+// sortGroups 按分组键排序，使结果稳定
+func sortGroups(groups []models.DuplicateGroup) {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Key != groups[j].Key {
+			return groups[i].Key < groups[j].Key
+		}
+		return groups[i].Items[0].Path < groups[j].Items[0].Path
+	})
+}
+
+// unionFind 并查集，同时记录每个集合内最低的相似度
+type unionFind struct {
+	parent     []int
+	count      []int
+	similarity map[int]float64
+}
+
+func newUnionFind(n int) *unionFind {
+	u := &unionFind{parent: make([]int, n), count: make([]int, n), similarity: make(map[int]float64)}
+	for i := range u.parent {
+		u.parent[i] = i
+		u.count[i] = 1
+	}
+	return u
+}
+
+func (u *unionFind) find(i int) int {
+	for u.parent[i] != i {
+		u.parent[i] = u.parent[u.parent[i]]
+		i = u.parent[i]
+	}
+	return i
+}
+
+func (u *unionFind) size(i int) int {
+	return u.count[u.find(i)]
+}
+
+func (u *unionFind) union(a, b int, similarity float64) {
+	rootA, rootB := u.find(a), u.find(b)
+	lowest := similarity
+	for _, root := range []int{rootA, rootB} {
+		if value, ok := u.similarity[root]; ok && value < lowest {
+			lowest = value
+		}
+	}
+	if rootA != rootB {
+		u.parent[rootB] = rootA
+		u.count[rootA] += u.count[rootB]
+		delete(u.similarity, rootB)
+	}
+	u.similarity[rootA] = lowest
+}
+
+// ResolveDuplicates 处理一组重复版本：保留一个，其余移入保留版本的角色文件夹或删除
+func (h *CardsHandler) ResolveDuplicates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.ResolveDuplicatesRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	if req.Action != "move" && req.Action != "delete" {
+		writeErrorResponse(w, http.StatusBadRequest, "处理方式只能是 move 或 delete", nil)
+		return
+	}
+	if req.KeepPath == "" || len(req.OtherPaths) == 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少要保留或处理的版本", nil)
+		return
+	}
+
+	rootPath := filepath.Clean(h.config.CharactersRootPath)
+	for _, p := range append([]string{req.KeepPath}, req.OtherPaths...) {
+		if !withinRoot(rootPath, p) {
+			writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+			return
+		}
+		if !cardfile.IsCardFile(p) {
+			writeErrorResponse(w, http.StatusBadRequest, "只支持 PNG 和 CHARX 文件", nil)
+			return
+		}
+		if _, err := os.Stat(p); err != nil {
+			writeErrorResponse(w, http.StatusNotFound, "文件不存在: "+filepath.Base(p), err)
+			return
+		}
+	}
+	for _, p := range req.OtherPaths {
+		if filepath.Clean(p) == filepath.Clean(req.KeepPath) {
+			writeErrorResponse(w, http.StatusBadRequest, "保留的版本不能同时被处理", nil)
+			return
+		}
+	}
+	defer h.cacheManager.Save()
+
+	keepFolder := filepath.Dir(req.KeepPath)
+	handled := make([]string, 0, len(req.OtherPaths))
+	failed := make(map[string]string)
+	steps := make([]journal.Step, 0, len(req.OtherPaths))
+	for _, p := range req.OtherPaths {
+		var versionSteps []journal.Step
+		var err error
+		if req.Action == "move" {
+			var dstPath string
+			if dstPath, versionSteps, err = h.moveVersion(p, keepFolder); err == nil {
+				handled = append(handled, dstPath)
+			}
+		} else {
+			if versionSteps, err = h.deleteVersion(p); err == nil {
+				handled = append(handled, p)
+			}
+		}
+		if err == nil {
+			steps = append(steps, versionSteps...)
+		}
+		if err != nil {
+			slog.Warn("处理重复版本失败", "文件", p, "error", err)
+			failed[p] = err.Error()
+		}
+	}
+
+	actionName := "删除"
+	if req.Action == "move" {
+		actionName = "移动"
+	}
+	if len(steps) > 0 {
+		h.journal.Record(opResolveDuplicates, fmt.Sprintf("保留 %s，%s %d 个重复版本", filepath.Base(req.KeepPath), actionName, len(steps)), steps...)
+	}
+	slog.Info("🧹 重复版本已处理", "保留", filepath.Base(req.KeepPath), "方式", actionName, "成功", len(handled), "失败", len(failed))
+	data := map[string]interface{}{"handled": handled, "failed": failed}
+	if len(failed) > 0 {
+		writeErrorResponseWithData(w, http.StatusInternalServerError, fmt.Sprintf("已%s %d 个版本，%d 个失败", actionName, len(handled), len(failed)), nil, data)
+		return
+	}
+	writeSuccessResponse(w, fmt.Sprintf("已保留 %s，%s了 %d 个重复版本", filepath.Base(req.KeepPath), actionName, len(handled)), data)
+}
+
+// moveVersion 将版本文件移入目标文件夹，并把它的标签和备注一并带过去，返回新路径和操作步骤
+//
+// 文件已在目标文件夹中时不产生步骤。
+func (h *CardsHandler) moveVersion(srcPath, dstFolder string) (string, []journal.Step, error) {
+	srcFolder := filepath.Dir(srcPath)
+	if srcFolder == dstFolder {
+		return srcPath, nil, nil
+	}
+	metadata, _ := h.getCardMetadata(srcPath)
+	fileName := filepath.Base(srcPath)
+	dstPath, err := uniqueFilePath(dstFolder, fileName)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.Rename(srcPath, dstPath); err != nil {
+		return "", nil, err
+	}
+	steps := []journal.Step{journal.Moved(srcPath, dstPath)}
+
+	// 备注写入目标文件夹成功后才从原文件夹删除，失败时保留原记录
+	noteMoved := false
+	notes, err := sidecar.Load(srcFolder)
+	if err != nil {
+		slog.Warn("读取版本备注失败", "文件", fileName, "error", err)
+	} else if note := notes.MatchVersions([]sidecar.VersionKey{{Hash: metadata.Hash, FileName: fileName}})[0]; note != nil {
+		label, comment := note.Label, note.Comment
+		step, err := updateSidecar(h.trash, dstFolder, func(s *sidecar.Sidecar) error {
+			s.SetVersionNote(metadata.Hash, filepath.Base(dstPath), label, comment)
+			return nil
+		})
+		if err != nil {
+			slog.Warn("迁移版本备注失败", "文件", fileName, "error", err)
+		} else {
+			noteMoved = true
+			if step.Kind != "" {
+				steps = append(steps, step)
+			}
+		}
+	}
+
+	if folderSteps := trashEmptyCharacterFolder(h.trash, h.config.CharactersRootPath, srcFolder); len(folderSteps) > 0 {
+		return dstPath, append(steps, folderSteps...), nil
+	}
+	if noteMoved {
+		if step, err := removeVersionNote(h.trash, srcFolder, metadata.Hash, fileName); err != nil {
+			slog.Warn("删除原文件夹中的版本备注失败", "文件", fileName, "error", err)
+		} else if step.Kind != "" {
+			steps = append(steps, step)
+		}
+	}
+	return dstPath, steps, nil
+}
+
+// deleteVersion 将版本文件移入回收站
+func (h *CardsHandler) deleteVersion(filePath string) ([]journal.Step, error) {
+	metadata, _ := h.getCardMetadata(filePath)
+	return trashVersion(h.trash, h.config.CharactersRootPath, filePath, metadata.Hash)
+}
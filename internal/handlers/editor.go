@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/card"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/journal"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// EditCard 按字段部分更新角色卡，结果保存为同一角色的新版本，原文件保持不变
+func (h *CardsHandler) EditCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.EditCardRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	if req.VersionPath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少版本文件路径", nil)
+		return
+	}
+	if !withinRoot(h.config.CharactersRootPath, req.VersionPath) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+
+	// 只接受可编辑的字段，拼写错误的字段名不应被静默忽略
+	var updates models.CardFieldUpdates
+	decoder := json.NewDecoder(bytes.NewReader(req.Fields))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&updates); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "字段更新格式无效或包含不可编辑的字段", err)
+		return
+	}
+
+	parsed, ok := loadVersionCard(w, req.VersionPath)
+	if !ok {
+		return
+	}
+	updatedFields := applyCardUpdates(parsed, updates)
+	if len(updatedFields) == 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "没有需要更新的字段", nil)
+		return
+	}
+	if err := parsed.Validate(); err != nil {
+		writeErrorResponseWithData(w, http.StatusBadRequest, "角色卡校验失败", err, map[string]interface{}{
+			"fieldErrors": card.FieldErrors(err),
+		})
+		return
+	}
+
+	extension := filepath.Ext(req.VersionPath)
+	outputFileName := filepath.Base(req.OutputFileName)
+	if req.OutputFileName == "" {
+		outputFileName = strings.TrimSuffix(filepath.Base(req.VersionPath), extension) + "_edited"
+	}
+	if !strings.EqualFold(filepath.Ext(outputFileName), extension) {
+		outputFileName += extension
+	}
+	outputPath, err := uniqueFilePath(filepath.Dir(req.VersionPath), outputFileName)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error(), err)
+		return
+	}
+
+	if err := cardfile.Save(req.VersionPath, outputPath, parsed); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("保存角色卡失败: %v", err), err)
+		return
+	}
+	h.journal.Record(opEditCard, "编辑角色卡并保存为 "+filepath.Base(outputPath), journal.Created(outputPath))
+	defer h.cacheManager.Save()
+
+	slog.Info("✏️ 角色卡已编辑", "版本", filepath.Base(req.VersionPath), "字段", updatedFields, "新文件", filepath.Base(outputPath))
+	writeSuccessResponse(w, "编辑成功！新版本已保存为: "+filepath.Base(outputPath), models.EditCardResponse{
+		Version:       h.cardVersion(outputPath),
+		UpdatedFields: updatedFields,
+	})
+}
+
+// applyCardUpdates 将字段更新应用到角色卡，返回实际提供的字段名
+func applyCardUpdates(c *card.Card, updates models.CardFieldUpdates) []string {
+	updated := make([]string, 0)
+	setString := func(name string, target *string, value *string) {
+		if value != nil {
+			*target = *value
+			updated = append(updated, name)
+		}
+	}
+	setList := func(name string, target *[]string, value *[]string) {
+		if value != nil {
+			*target = append([]string{}, *value...)
+			updated = append(updated, name)
+		}
+	}
+
+	setString("name", &c.Data.Name, updates.Name)
+	setString("description", &c.Data.Description, updates.Description)
+	setString("personality", &c.Data.Personality, updates.Personality)
+	setString("scenario", &c.Data.Scenario, updates.Scenario)
+	setString("first_mes", &c.Data.FirstMes, updates.FirstMes)
+	setList("alternate_greetings", &c.Data.AlternateGreetings, updates.AlternateGreetings)
+	setString("system_prompt", &c.Data.SystemPrompt, updates.SystemPrompt)
+	setList("tags", &c.Data.Tags, updates.Tags)
+	setString("creator_notes", &c.Data.CreatorNotes, updates.CreatorNotes)
+
+	if len(updated) > 0 && c.Version() == card.V3 {
+		now := time.Now().Unix()
+		c.Data.ModificationDate = &now
+	}
+	return updated
+}
@@ -1,18 +1,27 @@
 package handlers
 
 import (
+	"bytes"
 	"card-manager/internal/config"
 	"card-manager/internal/models"
 	"card-manager/internal/pkg/cache"
+	"card-manager/internal/pkg/card"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/charx"
+	"card-manager/internal/pkg/imaging"
+	"card-manager/internal/pkg/journal"
 	"card-manager/internal/pkg/png"
-	"encoding/base64"
+	"card-manager/internal/pkg/trash"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -22,13 +31,17 @@ import (
 type FilesHandler struct {
 	config       *config.Config
 	cacheManager *cache.Manager
+	trash        *trash.Bin
+	journal      *journal.Journal
 }
 
 // NewFilesHandler 创建新的文件处理器
-func NewFilesHandler(config *config.Config, cacheManager *cache.Manager) *FilesHandler {
+func NewFilesHandler(config *config.Config, cacheManager *cache.Manager, trashBin *trash.Bin, operations *journal.Journal) *FilesHandler {
 	return &FilesHandler{
 		config:       config,
 		cacheManager: cacheManager,
+		trash:        trashBin,
+		journal:      operations,
 	}
 }
 
@@ -45,15 +58,69 @@ func (h *FilesHandler) GetImage(w http.ResponseWriter, r *http.Request) {
 	cleanImagePath := filepath.Clean(imagePath)
 	cleanRootPath := filepath.Clean(h.config.CharactersRootPath)
 	
-	if !strings.HasPrefix(cleanImagePath, cleanRootPath) {
+	if !withinRoot(cleanRootPath, cleanImagePath) {
 		slog.Warn("图片路径验证失败", "请求路径", cleanImagePath, "根目录", cleanRootPath)
 		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
 		return
 	}
 	
+	// CHARX 包返回其中的头像图片
+	if cardfile.IsCharx(imagePath) {
+		h.serveCharxAvatar(w, r, imagePath)
+		return
+	}
+	
 	http.ServeFile(w, r, imagePath)
 }
 
+// serveCharxAvatar 提供 CHARX 包中的头像图片
+func (h *FilesHandler) serveCharxAvatar(w http.ResponseWriter, r *http.Request, charxPath string) {
+	stats, err := os.Stat(charxPath)
+	if err != nil {
+		writeErrorResponse(w, http.StatusNotFound, "文件不存在", err)
+		return
+	}
+	avatar, err := charx.ReadAvatar(charxPath)
+	if err != nil {
+		writeErrorResponse(w, http.StatusUnprocessableEntity, "无法读取 CHARX 头像", err)
+		return
+	}
+	http.ServeContent(w, r, "avatar."+imaging.Extension(avatar), stats.ModTime(), bytes.NewReader(avatar))
+}
+
+// GetCharxAsset 提供 CHARX 包中 embeded:// URI 指向的资源
+func (h *FilesHandler) GetCharxAsset(w http.ResponseWriter, r *http.Request) {
+	charxPath := r.URL.Query().Get("path")
+	uri := r.URL.Query().Get("uri")
+	if charxPath == "" || uri == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少路径或 URI 参数", nil)
+		return
+	}
+	if !cardfile.IsCharx(charxPath) {
+		writeErrorResponse(w, http.StatusBadRequest, "只能从 CHARX 文件中读取资源", nil)
+		return
+	}
+
+	stats, err := os.Stat(charxPath)
+	if err != nil {
+		writeErrorResponse(w, http.StatusNotFound, "文件不存在", err)
+		return
+	}
+	data, err := charx.ReadAsset(charxPath, uri)
+	if err != nil {
+		switch {
+		case errors.Is(err, charx.ErrAssetNotFound):
+			writeErrorResponse(w, http.StatusNotFound, "资源不存在", err)
+		case errors.Is(err, charx.ErrUnsupportedURI):
+			writeErrorResponse(w, http.StatusBadRequest, "不支持的资源 URI", err)
+		default:
+			writeErrorResponse(w, http.StatusInternalServerError, "读取资源失败", err)
+		}
+		return
+	}
+	http.ServeContent(w, r, path.Base(uri), stats.ModTime(), bytes.NewReader(data))
+}
+
 // OpenFolder 在系统文件管理器中打开文件夹
 func (h *FilesHandler) OpenFolder(w http.ResponseWriter, r *http.Request) {
 	var req models.OpenFolderRequest
@@ -135,22 +202,17 @@ func (h *FilesHandler) DownloadCard(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := os.MkdirAll(targetFolderPath, os.ModePerm); err != nil {
+	dirSteps, err := makeDirs(targetFolderPath)
+	if err != nil {
 		slog.Error("创建目录失败", "path", targetFolderPath, "error", err)
 		writeErrorResponse(w, http.StatusInternalServerError, "创建目录失败", err)
 		return
 	}
 
-	filePath := filepath.Join(targetFolderPath, finalFileName)
-	counter := 1
-	baseName := strings.TrimSuffix(finalFileName, filepath.Ext(finalFileName))
-	extension := filepath.Ext(finalFileName)
-	for {
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			break
-		}
-		filePath = filepath.Join(targetFolderPath, fmt.Sprintf("%s_%d%s", baseName, counter, extension))
-		counter++
+	filePath, err := uniqueFilePath(targetFolderPath, finalFileName)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error(), err)
+		return
 	}
 
 	file, err := os.Create(filePath)
@@ -167,37 +229,68 @@ func (h *FilesHandler) DownloadCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.journal.Record(opDownloadCard, "下载 "+filepath.Base(filePath), append(dirSteps, journal.Created(filePath))...)
 	slog.Info("📥 文件下载完成", "文件", filepath.Base(filePath), "大小", fmt.Sprintf("%.2f KB", float64(resp.ContentLength)/1024))
 	writeSuccessResponse(w, fmt.Sprintf("%s: %s", successMessage, filepath.Base(filePath)), nil)
 }
 
-// DeleteVersion 删除卡片版本
+// DeleteVersion 删除卡片版本，文件移入回收站
 func (h *FilesHandler) DeleteVersion(w http.ResponseWriter, r *http.Request) {
 	var req models.DeleteVersionRequest
 	if err := decodeJSONRequest(r, &req); err != nil {
 		handleAppError(w, err.(*models.AppError))
 		return
 	}
+	if !withinRoot(h.config.CharactersRootPath, req.FilePath) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
 	
 	fileName := filepath.Base(req.FilePath)
-	if err := os.Remove(req.FilePath); err != nil {
+	metadata, _ := h.cacheManager.Get(req.FilePath)
+	steps, err := trashVersion(h.trash, h.config.CharactersRootPath, req.FilePath, metadata.Hash)
+	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "删除文件失败", err)
 		return
 	}
+	h.journal.Record(opDeleteVersion, "删除版本 "+fileName, steps...)
 	
-	// 检查父目录是否为空，如果为空则删除
-	parentDir := filepath.Dir(req.FilePath)
-	files, err := os.ReadDir(parentDir)
-	if err == nil && len(files) == 0 {
-		if err := os.Remove(parentDir); err != nil {
-			slog.Warn("删除空目录失败", "目录", parentDir, "error", err)
-		} else {
-			slog.Info("🗑️ 空目录已清理", "目录", filepath.Base(parentDir))
-		}
+	slog.Info("🗑️ 文件已移入回收站", "文件", fileName)
+	writeSuccessResponse(w, fmt.Sprintf("文件 %s 已移入回收站", fileName), nil)
+}
+
+// DeleteCharacter 删除整个角色，角色文件夹移入回收站
+func (h *FilesHandler) DeleteCharacter(w http.ResponseWriter, r *http.Request) {
+	var req models.DeleteCharacterRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
 	}
 	
-	slog.Info("🗑️ 文件已删除", "文件", fileName)
-	writeSuccessResponse(w, fmt.Sprintf("文件 %s 已成功删除", fileName), nil)
+	// 只允许删除分类中的角色文件夹，子分类通过分类管理删除
+	if folderDepth(h.config.CharactersRootPath, req.FolderPath) < 2 {
+		writeErrorResponse(w, http.StatusForbidden, "只能删除角色文件夹", nil)
+		return
+	}
+	if info, err := os.Stat(req.FolderPath); err != nil || !info.IsDir() {
+		writeErrorResponse(w, http.StatusNotFound, "角色文件夹不存在", err)
+		return
+	}
+	if classifyFolder(req.FolderPath) == folderCategory {
+		writeErrorResponse(w, http.StatusBadRequest, "这是一个分类，请通过分类管理删除", nil)
+		return
+	}
+	
+	characterName := filepath.Base(req.FolderPath)
+	item, err := h.trash.Trash(req.FolderPath, trash.KindCharacter)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "删除角色失败", err)
+		return
+	}
+	h.journal.Record(opDeleteCharacter, "删除角色 "+characterName, journal.Trashed(req.FolderPath, item.ID))
+	
+	slog.Info("🗑️ 角色已移入回收站", "角色", characterName)
+	writeSuccessResponse(w, fmt.Sprintf("角色 %s 已移入回收站", characterName), nil)
 }
 
 // MoveCharacter 移动角色到不同分类
@@ -208,12 +301,31 @@ func (h *FilesHandler) MoveCharacter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	// 路径来自请求体，中间件不会检查
+	rootPath := filepath.Clean(h.config.CharactersRootPath)
+	req.OldFolderPath = filepath.Clean(req.OldFolderPath)
+	if !withinRoot(rootPath, req.OldFolderPath) || folderDepth(rootPath, req.OldFolderPath) < 2 {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+	
+	category, reason := normalizeCategoryPath(req.NewCategory)
+	if reason == "" {
+		reason = checkCategoryFolder(h.config.CharactersRootPath, filepath.Join(h.config.CharactersRootPath, filepath.FromSlash(category)))
+	}
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	req.NewCategory = category
+	
 	characterName := filepath.Base(req.OldFolderPath)
-	newFolderPath := filepath.Join(h.config.CharactersRootPath, req.NewCategory, characterName)
+	newFolderPath := filepath.Join(h.config.CharactersRootPath, filepath.FromSlash(req.NewCategory), characterName)
 	
 	// 确保目标分类目录存在
-	categoryPath := filepath.Join(h.config.CharactersRootPath, req.NewCategory)
-	if err := os.MkdirAll(categoryPath, 0755); err != nil {
+	categoryPath := filepath.Join(h.config.CharactersRootPath, filepath.FromSlash(req.NewCategory))
+	steps, err := makeDirs(categoryPath)
+	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "创建分类目录失败", err)
 		return
 	}
@@ -222,6 +334,10 @@ func (h *FilesHandler) MoveCharacter(w http.ResponseWriter, r *http.Request) {
 		writeErrorResponse(w, http.StatusInternalServerError, "移动角色失败", err)
 		return
 	}
+	h.cacheManager.Rekey(req.OldFolderPath, newFolderPath)
+	defer h.cacheManager.Save()
+	steps = append(steps, journal.Moved(req.OldFolderPath, newFolderPath))
+	h.journal.Record(opMoveCharacter, fmt.Sprintf("移动角色 %s 到 %s", characterName, req.NewCategory), steps...)
 	
 	slog.Info("📦 角色已移动", "角色", characterName, "从", filepath.Base(filepath.Dir(req.OldFolderPath)), "到", req.NewCategory)
 	writeSuccessResponse(w, fmt.Sprintf("角色 %s 已成功移动到 %s 分类", characterName, req.NewCategory), nil)
@@ -235,8 +351,34 @@ func (h *FilesHandler) OrganizeStray(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	newFolderPath := filepath.Join(h.config.CharactersRootPath, req.Category, req.CharacterName)
-	if err := os.MkdirAll(newFolderPath, 0755); err != nil {
+	// 路径来自请求体，中间件不会检查
+	rootPath := filepath.Clean(h.config.CharactersRootPath)
+	req.StrayPath = filepath.Clean(req.StrayPath)
+	if !withinRoot(rootPath, req.StrayPath) || folderDepth(rootPath, req.StrayPath) < 2 {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+	if !cardfile.IsCardFile(req.StrayPath) {
+		writeErrorResponse(w, http.StatusBadRequest, "只支持 PNG 和 CHARX 文件", nil)
+		return
+	}
+	
+	category, reason := normalizeCategoryPath(req.Category)
+	if reason == "" {
+		reason = validateFolderName(req.CharacterName)
+	}
+	if reason == "" {
+		reason = checkCategoryFolder(h.config.CharactersRootPath, filepath.Join(h.config.CharactersRootPath, filepath.FromSlash(category)))
+	}
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	req.Category = category
+	
+	newFolderPath := filepath.Join(h.config.CharactersRootPath, filepath.FromSlash(req.Category), req.CharacterName)
+	steps, err := makeDirs(newFolderPath)
+	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "创建角色目录失败", err)
 		return
 	}
@@ -246,12 +388,14 @@ func (h *FilesHandler) OrganizeStray(w http.ResponseWriter, r *http.Request) {
 		writeErrorResponse(w, http.StatusInternalServerError, "整理文件失败", err)
 		return
 	}
+	steps = append(steps, journal.Moved(req.StrayPath, newFilePath))
+	h.journal.Record(opOrganizeStray, fmt.Sprintf("整理 %s 到 %s/%s", filepath.Base(req.StrayPath), req.Category, req.CharacterName), steps...)
 	
 	slog.Info("📋 卡片已整理", "文件", filepath.Base(req.StrayPath), "角色", req.CharacterName, "分类", req.Category)
 	writeSuccessResponse(w, fmt.Sprintf("卡片已成功整理到 %s/%s", req.Category, req.CharacterName), nil)
 }
 
-// DeleteStray 删除待整理的卡片
+// DeleteStray 删除待整理的卡片，文件移入回收站
 func (h *FilesHandler) DeleteStray(w http.ResponseWriter, r *http.Request) {
 	var req models.DeleteVersionRequest // 复用相同的结构
 	if err := decodeJSONRequest(r, &req); err != nil {
@@ -267,13 +411,15 @@ func (h *FilesHandler) DeleteStray(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	fileName := filepath.Base(req.FilePath)
-	if err := os.Remove(req.FilePath); err != nil {
+	item, err := h.trash.Trash(req.FilePath, trash.KindStray)
+	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "删除文件失败", err)
 		return
 	}
+	h.journal.Record(opDeleteStray, "删除待整理文件 "+fileName, journal.Trashed(req.FilePath, item.ID))
 	
-	slog.Info("🗑️ 待整理文件已删除", "文件", fileName)
-	writeSuccessResponse(w, fmt.Sprintf("待整理文件 %s 已成功删除", fileName), nil)
+	slog.Info("🗑️ 待整理文件已移入回收站", "文件", fileName)
+	writeSuccessResponse(w, fmt.Sprintf("待整理文件 %s 已移入回收站", fileName), nil)
 }
 
 // ListFiles 列出文件夹中的文件
@@ -284,7 +430,7 @@ func (h *FilesHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	if !strings.HasPrefix(folderPath, h.config.CharactersRootPath) {
+	if !withinRoot(h.config.CharactersRootPath, folderPath) {
 		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
 		return
 	}
@@ -303,6 +449,9 @@ func (h *FilesHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		fileName := file.Name()
+		if strings.HasPrefix(fileName, ".") {
+			continue
+		}
 		if strings.HasSuffix(strings.ToLower(fileName), ".json") {
 			jsonFiles = append(jsonFiles, fileName)
 		} else if strings.HasSuffix(strings.ToLower(fileName), ".png") {
@@ -330,7 +479,7 @@ func (h *FilesHandler) MergeJsonToPng(w http.ResponseWriter, r *http.Request) {
 	pngPath := filepath.Join(req.FolderPath, req.PngFileName)
 
 	// 安全检查
-	if !strings.HasPrefix(jsonPath, h.config.CharactersRootPath) || !strings.HasPrefix(pngPath, h.config.CharactersRootPath) {
+	if !withinRoot(h.config.CharactersRootPath, jsonPath) || !withinRoot(h.config.CharactersRootPath, pngPath) {
 		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
 		return
 	}
@@ -342,20 +491,312 @@ func (h *FilesHandler) MergeJsonToPng(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 将 JSON 数据编码为 Base64
-	charaData := base64.StdEncoding.EncodeToString(jsonData)
+	// 按角色卡规范校验 JSON
+	parsedCard, err := card.ParseAndValidate(jsonData)
+	if err != nil {
+		writeErrorResponseWithData(w, http.StatusBadRequest, fmt.Sprintf("JSON 不是有效的角色卡: %v", err), err,
+			map[string]interface{}{"fieldErrors": card.FieldErrors(err)})
+		return
+	}
+
+	charaV2, charaV3, err := parsedCard.EncodeChunks()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "编码角色卡数据失败", err)
+		return
+	}
 
-	// 定义输出文件名
-	outputFileName := strings.TrimSuffix(req.PngFileName, filepath.Ext(req.PngFileName)) + "_merged.png"
+	// 确定输出文件
+	outputFileName := req.OutputFileName
+	switch {
+	case outputFileName != "":
+		if filepath.Base(outputFileName) != outputFileName {
+			writeErrorResponse(w, http.StatusBadRequest, "输出文件名不能包含路径", nil)
+			return
+		}
+		if !strings.HasSuffix(strings.ToLower(outputFileName), ".png") {
+			outputFileName += ".png"
+		}
+	case req.Overwrite:
+		outputFileName = req.PngFileName
+	default:
+		outputFileName = strings.TrimSuffix(req.PngFileName, filepath.Ext(req.PngFileName)) + "_merged.png"
+	}
 	outputPath := filepath.Join(req.FolderPath, outputFileName)
+	if !req.Overwrite {
+		var err error
+		outputPath, err = uniqueFilePath(req.FolderPath, outputFileName)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, err.Error(), err)
+			return
+		}
+		outputFileName = filepath.Base(outputPath)
+	}
 
-	// 调用PNG工具函数合并数据
-	err = png.WriteCharaToPNG(pngPath, outputPath, charaData)
+	backupID, err := backupBeforeWrite(h.trash, outputPath)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error(), err)
+		return
+	}
+
+	// 写入 chara（V2）和 ccv3（V3）两个数据块
+	err = png.WriteCharacterData(pngPath, outputPath, charaV2, charaV3)
 	if err != nil {
 		slog.Error("合并 JSON 到 PNG 失败", "error", err)
 		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("合并失败: %v", err), err)
 		return
 	}
+	h.journal.Record(opMergeJsonToPng, fmt.Sprintf("合并 %s 到 %s", req.JsonFileName, outputFileName), writtenStep(outputPath, backupID))
+
+	slog.Info("🔗 JSON 已合并到 PNG", "文件", outputFileName, "规范", parsedCard.Version().String())
+	writeSuccessResponse(w, "合并成功！新文件已保存为: "+outputFileName, map[string]string{
+		"fileName": outputFileName,
+		"path":     outputPath,
+	})
+}
+
+// ConvertCard 在 PNG 和 CHARX 格式之间转换角色卡，结果保存在原文件旁边
+func (h *FilesHandler) ConvertCard(w http.ResponseWriter, r *http.Request) {
+	var req models.ConvertCardRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+
+	if !withinRoot(h.config.CharactersRootPath, req.Path) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+	if _, err := os.Stat(req.Path); err != nil {
+		writeErrorResponse(w, http.StatusNotFound, "文件不存在", err)
+		return
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(req.Path), filepath.Ext(req.Path))
+	folderPath := filepath.Dir(req.Path)
+	var outputPath string
+	var err error
+	switch {
+	case cardfile.IsCharx(req.Path):
+		if outputPath, err = uniqueFilePath(folderPath, baseName+".png"); err == nil {
+			err = charx.ToPNG(req.Path, outputPath)
+		}
+	case strings.EqualFold(filepath.Ext(req.Path), ".png"):
+		if outputPath, err = uniqueFilePath(folderPath, baseName+charx.Extension); err == nil {
+			err = charx.FromPNG(req.Path, outputPath)
+		}
+	default:
+		writeErrorResponse(w, http.StatusBadRequest, "只支持 PNG 和 CHARX 文件", nil)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("转换失败: %v", err), err)
+		return
+	}
+	h.journal.Record(opConvertCard, fmt.Sprintf("转换 %s 为 %s", filepath.Base(req.Path), filepath.Base(outputPath)), journal.Created(outputPath))
+
+	slog.Info("🔄 角色卡格式已转换", "源文件", filepath.Base(req.Path), "新文件", filepath.Base(outputPath))
+	writeSuccessResponse(w, "转换成功！新文件已保存为: "+filepath.Base(outputPath), map[string]string{
+		"fileName": filepath.Base(outputPath),
+		"path":     outputPath,
+	})
+}
+
+// ExtractCardJson 导出角色卡版本中的 JSON
+//
+// GET 以附件形式直接下载，POST 将格式化后的 JSON 保存到版本文件旁边。
+func (h *FilesHandler) ExtractCardJson(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		h.downloadCardJson(w, r)
+	} else if r.Method == http.MethodPost {
+		h.saveCardJson(w, r)
+	} else {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+	}
+}
+
+// downloadCardJson 以附件形式返回角色卡 JSON
+func (h *FilesHandler) downloadCardJson(w http.ResponseWriter, r *http.Request) {
+	cardPath := r.URL.Query().Get("path")
+	if cardPath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少路径参数", nil)
+		return
+	}
+
+	data, ok := h.extractCardJson(w, cardPath)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": cardfile.JSONFileName(cardPath),
+	}))
+	w.Write(data)
+}
+
+// saveCardJson 将角色卡 JSON 保存到版本文件所在目录
+func (h *FilesHandler) saveCardJson(w http.ResponseWriter, r *http.Request) {
+	var req models.ExtractCardJsonRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	if req.Path == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少路径参数", nil)
+		return
+	}
+	if !withinRoot(h.config.CharactersRootPath, req.Path) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+
+	data, ok := h.extractCardJson(w, req.Path)
+	if !ok {
+		return
+	}
+
+	folderPath := filepath.Dir(req.Path)
+	outputPath := filepath.Join(folderPath, cardfile.JSONFileName(req.Path))
+	if !req.Overwrite {
+		var err error
+		outputPath, err = uniqueFilePath(folderPath, cardfile.JSONFileName(req.Path))
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, err.Error(), err)
+			return
+		}
+	}
+	backupID, err := backupBeforeWrite(h.trash, outputPath)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error(), err)
+		return
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "保存 JSON 文件失败", err)
+		return
+	}
+	h.journal.Record(opExtractCardJson, "导出 JSON "+filepath.Base(outputPath), writtenStep(outputPath, backupID))
 
-	writeSuccessResponse(w, "合并成功！新文件已保存为: "+outputFileName, nil)
-}
\ No newline at end of file
+	slog.Info("📤 角色卡 JSON 已导出", "源文件", filepath.Base(req.Path), "新文件", filepath.Base(outputPath))
+	writeSuccessResponse(w, "导出成功！JSON 已保存为: "+filepath.Base(outputPath), map[string]string{
+		"fileName": filepath.Base(outputPath),
+		"path":     outputPath,
+	})
+}
+
+// extractCardJson 读取并格式化版本文件中的角色卡 JSON，失败时直接写出错误响应
+func (h *FilesHandler) extractCardJson(w http.ResponseWriter, cardPath string) ([]byte, bool) {
+	if !cardfile.IsCardFile(cardPath) {
+		writeErrorResponse(w, http.StatusBadRequest, "只支持 PNG 和 CHARX 文件", nil)
+		return nil, false
+	}
+	if _, err := os.Stat(cardPath); err != nil {
+		writeErrorResponse(w, http.StatusNotFound, "文件不存在", err)
+		return nil, false
+	}
+
+	data, err := cardfile.PrettyJSON(cardPath)
+	if err != nil {
+		if errors.Is(err, png.ErrNoCharacterData) || errors.Is(err, charx.ErrNoCardJSON) {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, "文件中没有角色卡数据", err)
+		} else {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, fmt.Sprintf("无法解析角色卡数据: %v", err), err)
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+// ReplaceAvatar 用卡面或上传的图片替换版本头像，保存为同一角色的新版本
+func (h *FilesHandler) ReplaceAvatar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.ReplaceAvatarRequest
+	var imageData []byte
+	var imageName string
+	if isMultipartRequest(r) {
+		var ok bool
+		if imageData, imageName, ok = readUploadedFile(w, r); !ok {
+			return
+		}
+		req.VersionPath = r.FormValue("versionPath")
+		req.OutputFileName = r.FormValue("outputFileName")
+	} else {
+		if err := decodeJSONRequest(r, &req); err != nil {
+			handleAppError(w, err.(*models.AppError))
+			return
+		}
+		if req.FacePath == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "缺少卡面图片路径", nil)
+			return
+		}
+		if !withinRoot(h.config.CharactersRootPath, req.FacePath) {
+			writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+			return
+		}
+		var err error
+		if imageData, err = os.ReadFile(req.FacePath); err != nil {
+			writeErrorResponse(w, http.StatusNotFound, "卡面图片不存在", err)
+			return
+		}
+		imageName = filepath.Base(req.FacePath)
+	}
+
+	if req.VersionPath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少版本文件路径", nil)
+		return
+	}
+	if !withinRoot(h.config.CharactersRootPath, req.VersionPath) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+	if !cardfile.IsCardFile(req.VersionPath) {
+		writeErrorResponse(w, http.StatusBadRequest, "只支持 PNG 和 CHARX 文件", nil)
+		return
+	}
+	if _, err := os.Stat(req.VersionPath); err != nil {
+		writeErrorResponse(w, http.StatusNotFound, "版本文件不存在", err)
+		return
+	}
+	if imaging.Extension(imageData) == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "只支持 PNG、JPEG、GIF 和 WebP 图片", nil)
+		return
+	}
+
+	folderPath := filepath.Dir(req.VersionPath)
+	outputFileName := filepath.Base(req.OutputFileName)
+	if req.OutputFileName == "" {
+		versionBase := strings.TrimSuffix(filepath.Base(req.VersionPath), filepath.Ext(req.VersionPath))
+		imageBase := strings.TrimSuffix(filepath.Base(imageName), filepath.Ext(imageName))
+		if imageBase == "" || imageBase == "." {
+			imageBase = "avatar"
+		}
+		outputFileName = versionBase + "_" + imageBase
+	}
+	if !strings.EqualFold(filepath.Ext(outputFileName), ".png") {
+		outputFileName += ".png"
+	}
+	outputPath, err := uniqueFilePath(folderPath, outputFileName)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error(), err)
+		return
+	}
+
+	if err := cardfile.ReplaceAvatar(req.VersionPath, imageData, outputPath); err != nil {
+		if errors.Is(err, png.ErrNoCharacterData) || errors.Is(err, charx.ErrNoCardJSON) {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, "版本文件中没有角色卡数据", err)
+		} else {
+			writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("替换头像失败: %v", err), err)
+		}
+		return
+	}
+	h.journal.Record(opReplaceAvatar, "替换头像并保存为 "+filepath.Base(outputPath), journal.Created(outputPath))
+
+	slog.Info("🖼️ 角色卡头像已替换", "版本", filepath.Base(req.VersionPath), "图片", imageName, "新文件", filepath.Base(outputPath))
+	writeSuccessResponse(w, "头像替换成功！新版本已保存为: "+filepath.Base(outputPath), map[string]string{
+		"fileName": filepath.Base(outputPath),
+		"path":     outputPath,
+	})
+}
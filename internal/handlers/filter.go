@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/sidecar"
+	"card-manager/internal/pkg/tags"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// /api/cards 的 import 参数可用的导入状态
+const (
+	importNotImported = "not-imported"
+	importOutdated    = "outdated"
+	importImported    = "imported"
+	importLatest      = "latest"
+)
+
+// /api/cards 的 localization 参数可用的本地化状态
+const (
+	localizationPending   = "pending"
+	localizationDone      = "localized"
+	localizationNotNeeded = "not-needed"
+)
+
+// cardFilter 角色列表的筛选条件，未设置的条件不参与筛选
+type cardFilter struct {
+	// terms 搜索词，每个词都要出现在文件夹名、角色卡内的名称或作者中（不区分大小写）
+	terms []string
+	// category 分类路径，同时包括它的子分类
+	category      string
+	importState   string
+	localization  string
+	hasNote       *bool
+	hasFaces      *bool
+	modifiedSince time.Time
+	// tags 角色必须带有的全部标签（不区分大小写）
+	tags     []string
+	favorite *bool
+	// minRating 最低评分，0 表示不筛选
+	minRating int
+	// statuses 角色的状态为其中之一
+	statuses []string
+}
+
+// filterParam 筛选参数及其解析方法，values 为查询中该参数的所有取值
+type filterParam struct {
+	name  string
+	parse func(f *cardFilter, values []string) error
+}
+
+// filterParams parseCardFilter 支持的全部查询参数，智能收藏夹也只能使用这些参数
+var filterParams = []filterParam{
+	{"q", func(f *cardFilter, values []string) error {
+		f.terms = strings.Fields(strings.ToLower(values[0]))
+		return nil
+	}},
+	{"category", func(f *cardFilter, values []string) error {
+		if values[0] == "" {
+			return nil
+		}
+		categoryPath, reason := normalizeCategoryPath(values[0])
+		if reason != "" {
+			return models.NewBadRequestError(reason, nil)
+		}
+		f.category = categoryPath
+		return nil
+	}},
+	{"import", func(f *cardFilter, values []string) error {
+		switch values[0] {
+		case "", importNotImported, importOutdated, importImported, importLatest:
+			f.importState = values[0]
+			return nil
+		}
+		return models.NewBadRequestError(fmt.Sprintf("import 参数无效，可选值: %s、%s、%s、%s", importNotImported, importOutdated, importImported, importLatest), nil)
+	}},
+	{"localization", func(f *cardFilter, values []string) error {
+		switch values[0] {
+		case "", localizationPending, localizationDone, localizationNotNeeded:
+			f.localization = values[0]
+			return nil
+		}
+		return models.NewBadRequestError(fmt.Sprintf("localization 参数无效，可选值: %s、%s、%s", localizationPending, localizationDone, localizationNotNeeded), nil)
+	}},
+	{"hasNote", func(f *cardFilter, values []string) (err error) {
+		f.hasNote, err = parseBoolParam("hasNote", values[0])
+		return err
+	}},
+	{"hasFaces", func(f *cardFilter, values []string) (err error) {
+		f.hasFaces, err = parseBoolParam("hasFaces", values[0])
+		return err
+	}},
+	{"tag", func(f *cardFilter, values []string) error {
+		for _, value := range values {
+			tag, reason := tags.Normalize(value)
+			if reason != "" {
+				return models.NewBadRequestError("tag 参数无效: "+reason, nil)
+			}
+			f.tags = append(f.tags, tag)
+		}
+		return nil
+	}},
+	{"favorite", func(f *cardFilter, values []string) (err error) {
+		f.favorite, err = parseBoolParam("favorite", values[0])
+		return err
+	}},
+	{"minRating", func(f *cardFilter, values []string) (err error) {
+		if values[0] == "" {
+			return nil
+		}
+		f.minRating, err = strconv.Atoi(values[0])
+		if err != nil || f.minRating < 1 || f.minRating > sidecar.MaxRating {
+			return models.NewBadRequestError(fmt.Sprintf("minRating 参数无效，应为 1 到 %d", sidecar.MaxRating), err)
+		}
+		return nil
+	}},
+	{"status", func(f *cardFilter, values []string) error {
+		for _, value := range values {
+			for _, status := range strings.Split(value, ",") {
+				if statusRank(status) < 0 {
+					return models.NewBadRequestError("status 参数无效，可选值: "+strings.Join(sidecar.Statuses, "、"), nil)
+				}
+				f.statuses = append(f.statuses, status)
+			}
+		}
+		return nil
+	}},
+	{"modifiedSince", func(f *cardFilter, values []string) (err error) {
+		if values[0] == "" {
+			return nil
+		}
+		if f.modifiedSince, err = parseTimeParam(values[0]); err != nil {
+			return models.NewBadRequestError("modifiedSince 参数无效，应为 RFC 3339 时间或 YYYY-MM-DD 日期", err)
+		}
+		return nil
+	}},
+}
+
+// isFilterParam 判断查询参数是否为筛选参数
+func isFilterParam(name string) bool {
+	for _, param := range filterParams {
+		if param.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCardFilter 从查询参数解析筛选条件，没有任何筛选参数时返回 nil
+func parseCardFilter(query url.Values) (*cardFilter, error) {
+	filter := &cardFilter{}
+	for _, param := range filterParams {
+		values := query[param.name]
+		if len(values) == 0 {
+			continue
+		}
+		if err := param.parse(filter, values); err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.isEmpty() {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// parseBoolParam 解析布尔类型的查询参数 name 的值，值为空时返回 nil
+func parseBoolParam(name, value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, models.NewBadRequestError(name+" 参数无效，应为 true 或 false", err)
+	}
+	return &parsed, nil
+}
+
+// parseTimeParam 解析 RFC 3339 时间或本地时区的日期
+func parseTimeParam(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// isEmpty 判断是否没有设置任何筛选条件
+func (f *cardFilter) isEmpty() bool {
+	return len(f.terms) == 0 && f.category == "" && f.importState == "" && f.localization == "" &&
+		f.hasNote == nil && f.hasFaces == nil && f.modifiedSince.IsZero() && len(f.tags) == 0 &&
+		f.favorite == nil && f.minRating == 0 && len(f.statuses) == 0
+}
+
+// matchCategory 判断分类是否在筛选的分类或其子分类中
+func (f *cardFilter) matchCategory(categoryPath string) bool {
+	if f == nil || f.category == "" {
+		return true
+	}
+	return strings.EqualFold(categoryPath, f.category) || strings.HasPrefix(strings.ToLower(categoryPath), strings.ToLower(f.category)+"/")
+}
+
+// matchCharacter 判断角色是否满足分类以外的筛选条件
+func (f *cardFilter) matchCharacter(character *models.Character) bool {
+	if f == nil {
+		return true
+	}
+	if !matchTerms(f.terms, character.Name, character.InternalName, character.Creator) {
+		return false
+	}
+
+	imported := character.ImportInfo
+	switch f.importState {
+	case importNotImported:
+		if imported.IsImported {
+			return false
+		}
+	case importOutdated:
+		if !imported.IsImported || imported.IsLatestImported {
+			return false
+		}
+	case importImported:
+		if !imported.IsImported {
+			return false
+		}
+	case importLatest:
+		if !imported.IsLatestImported {
+			return false
+		}
+	}
+
+	needed := character.LocalizationNeeded != nil && *character.LocalizationNeeded
+	switch f.localization {
+	case localizationPending:
+		if !needed || character.IsLocalized {
+			return false
+		}
+	case localizationDone:
+		if !character.IsLocalized {
+			return false
+		}
+	case localizationNotNeeded:
+		if needed {
+			return false
+		}
+	}
+
+	if f.hasNote != nil && character.HasNote != *f.hasNote {
+		return false
+	}
+	if f.hasFaces != nil && character.HasFaceFolder != *f.hasFaces {
+		return false
+	}
+	if !f.modifiedSince.IsZero() && !latestModified(character).After(f.modifiedSince) {
+		return false
+	}
+	for _, tag := range f.tags {
+		if !tags.Contains(character.Tags, tag) {
+			return false
+		}
+	}
+	if f.favorite != nil && character.Favorite != *f.favorite {
+		return false
+	}
+	if character.Rating < f.minRating {
+		return false
+	}
+	if len(f.statuses) > 0 && !slices.Contains(f.statuses, character.Status) {
+		return false
+	}
+	return true
+}
+
+// matchStray 判断待整理卡片是否满足筛选条件，只按文件名和分类筛选，设置了其他条件时都不满足
+func (f *cardFilter) matchStray(stray models.StrayCard) bool {
+	if f == nil {
+		return true
+	}
+	if f.importState != "" || f.localization != "" || f.hasNote != nil || f.hasFaces != nil || !f.modifiedSince.IsZero() || len(f.tags) > 0 ||
+		f.favorite != nil || f.minRating > 0 || len(f.statuses) > 0 {
+		return false
+	}
+	return f.matchCategory(stray.Category) && matchTerms(f.terms, stray.FileName)
+}
+
+// matchTerms 判断每个搜索词都至少出现在一个字段中
+func matchTerms(terms []string, fields ...string) bool {
+	for _, term := range terms {
+		found := false
+		for _, field := range fields {
+			if strings.Contains(strings.ToLower(field), term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// latestModified 角色所有版本中最晚的修改时间
+func latestModified(character *models.Character) time.Time {
+	var latest time.Time
+	for _, version := range character.Versions {
+		if mtime, err := time.Parse(time.RFC3339Nano, version.Mtime); err == nil && mtime.After(latest) {
+			latest = mtime
+		}
+	}
+	return latest
+}
@@ -4,27 +4,51 @@ import (
 	"card-manager/internal/config"
 	"card-manager/internal/models"
 	"card-manager/internal/pkg/cache"
+	"card-manager/internal/pkg/card"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/charx"
+	"card-manager/internal/pkg/collections"
+	"card-manager/internal/pkg/fulltext"
+	"card-manager/internal/pkg/journal"
+	"card-manager/internal/pkg/png"
+	"card-manager/internal/pkg/sidecar"
+	"card-manager/internal/pkg/tags"
 	"card-manager/internal/pkg/tavern"
+	"card-manager/internal/pkg/trash"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 // Handlers 包含所有处理器
 type Handlers struct {
-	Cards  *CardsHandler
-	Files  *FilesHandler
-	Tavern *TavernHandler
-	System *SystemHandler
+	Cards      *CardsHandler
+	Files      *FilesHandler
+	Tavern     *TavernHandler
+	System     *SystemHandler
+	Lorebook   *LorebookHandler
+	Trash      *TrashHandler
+	Journal    *JournalHandler
+	Categories *CategoriesHandler
 }
 
 // NewHandlers 创建新的处理器集合
-func NewHandlers(config *config.Config, cacheManager *cache.Manager) *Handlers {
+func NewHandlers(config *config.Config, cacheManager *cache.Manager, trashBin *trash.Bin, operations *journal.Journal, index *fulltext.Index, tagStore *tags.Store, collectionStore *collections.Store) *Handlers {
 	return &Handlers{
-		Cards:  NewCardsHandler(config, cacheManager, nil), // 暂时传nil，稍后更新
-		Files:  NewFilesHandler(config, cacheManager),
-		Tavern: NewTavernHandler(config, cacheManager),
-		System: NewSystemHandler(config, cacheManager),
+		Cards:      NewCardsHandler(config, cacheManager, nil, trashBin, operations, index, tagStore, collectionStore), // 暂时传nil，稍后更新
+		Files:      NewFilesHandler(config, cacheManager, trashBin, operations),
+		Tavern:     NewTavernHandler(config, cacheManager, trashBin, operations),
+		System:     NewSystemHandler(config, cacheManager),
+		Lorebook:   NewLorebookHandler(config, trashBin, operations),
+		Trash:      NewTrashHandler(config, trashBin, operations),
+		Journal:    NewJournalHandler(operations, cacheManager),
+		Categories: NewCategoriesHandler(config, cacheManager, trashBin, operations),
 	}
 }
 
@@ -50,9 +74,15 @@ func writeSuccessResponse(w http.ResponseWriter, message string, data interface{
 
 // writeErrorResponse 写入错误响应
 func writeErrorResponse(w http.ResponseWriter, code int, message string, err error) {
+	writeErrorResponseWithData(w, code, message, err, nil)
+}
+
+// writeErrorResponseWithData 写入带附加数据的错误响应（如字段级错误）
+func writeErrorResponseWithData(w http.ResponseWriter, code int, message string, err error, data interface{}) {
 	response := models.APIResponse{
 		Success: false,
 		Message: message,
+		Data:    data,
 	}
 	
 	if err != nil {
@@ -78,4 +108,286 @@ func decodeJSONRequest(r *http.Request, v interface{}) error {
 		return models.NewBadRequestError("请求格式无效", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// maxUniqueNameAttempts 生成不冲突文件名时最多尝试的次数
+const maxUniqueNameAttempts = 10000
+
+// uniqueFilePath 在目录中为文件名生成不冲突的路径，重名时追加 _1、_2 等后缀
+func uniqueFilePath(dir, fileName string) (string, error) {
+	filePath := filepath.Join(dir, fileName)
+	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	extension := filepath.Ext(fileName)
+	for counter := 1; counter <= maxUniqueNameAttempts; counter++ {
+		_, err := os.Stat(filePath)
+		if os.IsNotExist(err) {
+			return filePath, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("检查文件 %s 失败: %w", filePath, err)
+		}
+		filePath = filepath.Join(dir, fmt.Sprintf("%s_%d%s", baseName, counter, extension))
+	}
+	return "", fmt.Errorf("无法在 %s 中为 %s 生成不冲突的文件名", dir, fileName)
+}
+
+// invalidNameChars 文件夹名中不允许出现的字符（按 Windows 的规则）
+const invalidNameChars = `\/:*?"<>|`
+
+// validateFolderName 检查能否用作文件夹名，返回不能使用的原因，可以使用时返回空串
+func validateFolderName(name string) string {
+	switch {
+	case name == "":
+		return "名称不能为空"
+	case strings.HasPrefix(name, "."):
+		return "名称不能以点开头"
+	case strings.HasSuffix(name, ".") || strings.HasSuffix(name, " "):
+		return "名称不能以点或空格结尾"
+	case strings.ContainsAny(name, invalidNameChars):
+		return "名称不能包含以下字符: " + invalidNameChars
+	}
+	return ""
+}
+
+// folderDepth 路径相对角色库根目录的层数，分类为 1，角色文件夹至少为 2；不在角色库中时返回 0
+func folderDepth(rootPath, path string) int {
+	rel, err := filepath.Rel(rootPath, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, ".") {
+		return 0
+	}
+	return len(strings.Split(rel, string(filepath.Separator)))
+}
+
+// makeDirs 创建目录及其缺少的上级目录，返回新建目录的操作步骤（从外到内）
+func makeDirs(dir string) ([]journal.Step, error) {
+	var missing []string
+	for current := filepath.Clean(dir); ; current = filepath.Dir(current) {
+		if _, err := os.Stat(current); err == nil || filepath.Dir(current) == current {
+			break
+		}
+		missing = append([]string{current}, missing...)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	steps := make([]journal.Step, 0, len(missing))
+	for _, created := range missing {
+		steps = append(steps, journal.MadeDir(created))
+	}
+	return steps, nil
+}
+
+// backupBeforeWrite 目标文件已存在时先复制到回收站，返回备份的条目 ID，文件不存在时返回空串
+func backupBeforeWrite(trashBin *trash.Bin, path string) (string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", nil
+	}
+	item, err := trashBin.Backup(path)
+	if err != nil {
+		return "", fmt.Errorf("备份原文件失败: %w", err)
+	}
+	return item.ID, nil
+}
+
+// writtenStep 根据写入前是否有备份，生成新建或覆盖文件的操作步骤
+func writtenStep(path, backupID string) journal.Step {
+	if backupID != "" {
+		return journal.Overwritten(path, backupID)
+	}
+	return journal.Created(path)
+}
+
+// journaledWrite 先备份 path 再执行 write，返回用于撤销的步骤
+//
+// 写入后文件不存在（如附属数据清空后被删除）时记为移入回收站；写入前后
+// 文件都不存在时返回没有类型的步骤，不需要记录。
+func journaledWrite(trashBin *trash.Bin, path string, write func() error) (journal.Step, error) {
+	backupID, err := backupBeforeWrite(trashBin, path)
+	if err != nil {
+		return journal.Step{}, err
+	}
+	if err := write(); err != nil {
+		if backupID != "" {
+			trashBin.Purge(backupID)
+		}
+		return journal.Step{}, err
+	}
+	return resultStep(path, backupID), nil
+}
+
+// resultStep 根据写入前的备份和写入后文件是否存在，生成撤销写入的步骤
+func resultStep(path, backupID string) journal.Step {
+	if _, err := os.Stat(path); err == nil {
+		return writtenStep(path, backupID)
+	}
+	if backupID != "" {
+		return journal.Trashed(path, backupID)
+	}
+	return journal.Step{}
+}
+
+// collapseWrites 把 journaledWrite 对同一文件多次写入的步骤合并为一个
+//
+// 只保留第一次写入前的备份，按文件的最终状态重新生成步骤，其余备份从回收站清除。
+func collapseWrites(trashBin *trash.Bin, steps []journal.Step) []journal.Step {
+	first := make(map[string]int, len(steps))
+	result := make([]journal.Step, 0, len(steps))
+	for _, step := range steps {
+		if step.Kind == "" {
+			continue
+		}
+		i, seen := first[step.Path]
+		if !seen {
+			first[step.Path] = len(result)
+			result = append(result, step)
+			continue
+		}
+		if step.TrashID != "" {
+			trashBin.Purge(step.TrashID)
+		}
+		backupID := ""
+		if result[i].Kind != journal.StepCreate {
+			backupID = result[i].TrashID
+		}
+		result[i] = resultStep(step.Path, backupID)
+	}
+	return result
+}
+
+// updateSidecar 修改角色文件夹的附属数据，返回撤销修改的步骤
+func updateSidecar(trashBin *trash.Bin, folderPath string, modify func(s *sidecar.Sidecar) error) (journal.Step, error) {
+	return journaledWrite(trashBin, filepath.Join(folderPath, sidecar.FileName), func() error {
+		return sidecar.Update(folderPath, modify)
+	})
+}
+
+// removeVersionNote 删除版本的标签和备注，没有记录时不修改附属数据
+func removeVersionNote(trashBin *trash.Bin, folderPath, hash, fileName string) (journal.Step, error) {
+	notes, err := sidecar.Load(folderPath)
+	if err != nil {
+		return journal.Step{}, err
+	}
+	if notes.MatchVersions([]sidecar.VersionKey{{Hash: hash, FileName: fileName}})[0] == nil {
+		return journal.Step{}, nil
+	}
+	return updateSidecar(trashBin, folderPath, func(s *sidecar.Sidecar) error {
+		s.SetVersionNote(hash, fileName, "", "")
+		return nil
+	})
+}
+
+// isImageFile 检查文件是否为图片文件，这些格式都能由 imaging.Decode 解码（WebP 解码器由 imaging 注册）
+func isImageFile(fileName string) bool {
+	lowerName := strings.ToLower(fileName)
+	return strings.HasSuffix(lowerName, ".jpg") ||
+		strings.HasSuffix(lowerName, ".jpeg") ||
+		strings.HasSuffix(lowerName, ".png") ||
+		strings.HasSuffix(lowerName, ".gif") ||
+		strings.HasSuffix(lowerName, ".webp")
+}
+
+// maxUploadSize 上传文件的大小上限
+const maxUploadSize = 32 << 20
+
+// isMultipartRequest 判断请求是否为 multipart 表单上传
+func isMultipartRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+}
+
+// readUploadedFile 解析 multipart 表单并读取 file 字段上传的文件，失败时直接写出错误响应
+func readUploadedFile(w http.ResponseWriter, r *http.Request) ([]byte, string, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "无法解析上传的表单", err)
+		return nil, "", false
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少上传的文件", err)
+		return nil, "", false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "读取上传的文件失败", err)
+		return nil, "", false
+	}
+	return data, header.Filename, true
+}
+
+// loadVersionCard 读取版本文件中的角色卡，失败时直接写出错误响应
+func loadVersionCard(w http.ResponseWriter, versionPath string) (*card.Card, bool) {
+	if !cardfile.IsCardFile(versionPath) {
+		writeErrorResponse(w, http.StatusBadRequest, "只支持 PNG 和 CHARX 文件", nil)
+		return nil, false
+	}
+	if _, err := os.Stat(versionPath); err != nil {
+		writeErrorResponse(w, http.StatusNotFound, "版本文件不存在", err)
+		return nil, false
+	}
+
+	parsed, err := cardfile.Load(versionPath)
+	if err != nil {
+		if errors.Is(err, png.ErrNoCharacterData) || errors.Is(err, charx.ErrNoCardJSON) {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, "文件中没有角色卡数据", err)
+		} else {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, fmt.Sprintf("无法解析角色卡数据: %v", err), err)
+		}
+		return nil, false
+	}
+	return parsed, true
+}
+
+// trashVersion 将版本文件移入回收站，返回需要记录的步骤
+//
+// 文件夹中没有其他版本时连同附属数据一并移入回收站，否则只删除该版本的标签和备注。
+func trashVersion(trashBin *trash.Bin, rootPath, filePath, hash string) ([]journal.Step, error) {
+	item, err := trashBin.Trash(filePath, trash.KindVersion)
+	if err != nil {
+		return nil, err
+	}
+	steps := []journal.Step{journal.Trashed(filePath, item.ID)}
+	folderPath := filepath.Dir(filePath)
+	if folderSteps := trashEmptyCharacterFolder(trashBin, rootPath, folderPath); len(folderSteps) > 0 {
+		return append(steps, folderSteps...), nil
+	}
+	if _, err := os.Stat(folderPath); err != nil {
+		return steps, nil
+	}
+	if step, err := removeVersionNote(trashBin, folderPath, hash, filepath.Base(filePath)); err != nil {
+		slog.Warn("删除版本备注失败", "文件", filepath.Base(filePath), "error", err)
+	} else if step.Kind != "" {
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// trashEmptyCharacterFolder 清理已经没有版本的角色文件夹，返回需要记录的步骤
+//
+// 完全为空的文件夹直接删除；只剩附属数据文件的连同附属数据一起移入回收站，
+// 以免标签、备注、评分等随之丢失，撤销时整个文件夹会先被恢复。分类目录不受影响。
+func trashEmptyCharacterFolder(trashBin *trash.Bin, rootPath, folderPath string) []journal.Step {
+	if folderDepth(rootPath, folderPath) < 2 {
+		return nil
+	}
+	files, err := os.ReadDir(folderPath)
+	if err != nil || len(files) > 1 || (len(files) == 1 && !sidecar.IsSidecar(files[0].Name())) {
+		return nil
+	}
+	if len(files) == 0 {
+		if err := os.Remove(folderPath); err != nil {
+			slog.Warn("删除空目录失败", "目录", folderPath, "error", err)
+		} else {
+			slog.Info("🗑️ 空目录已清理", "目录", filepath.Base(folderPath))
+		}
+		return nil
+	}
+	item, err := trashBin.Trash(folderPath, trash.KindCharacter)
+	if err != nil {
+		slog.Warn("清理空目录失败", "目录", folderPath, "error", err)
+		return nil
+	}
+	slog.Info("🗑️ 空目录已移入回收站", "目录", filepath.Base(folderPath))
+	return []journal.Step{journal.Trashed(folderPath, item.ID)}
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/cache"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/imaging"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultImageDistance 感知哈希相差不超过该位数时视为视觉相似
+const defaultImageDistance = 10
+
+// 相似图片的来源
+const (
+	imageKindCard = "card"
+	imageKindFace = "face"
+)
+
+// imageCandidate 参与相似图片比较的角色卡版本或卡面
+type imageCandidate struct {
+	match models.ImageMatch
+	hash  uint64
+	ok    bool
+}
+
+// SimilarImages 查找与角色库中指定角色卡或卡面视觉相似的图片
+func (h *CardsHandler) SimilarImages(w http.ResponseWriter, r *http.Request) {
+	imagePath := r.URL.Query().Get("path")
+	if imagePath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少图片路径", nil)
+		return
+	}
+	if !withinRoot(h.config.CharactersRootPath, imagePath) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+	if !cardfile.IsCardFile(imagePath) && !isImageFile(imagePath) {
+		writeErrorResponse(w, http.StatusBadRequest, "只支持角色卡和图片文件", nil)
+		return
+	}
+	maxDistance, ok := parseMaxDistance(w, r.URL.Query().Get("maxDistance"))
+	if !ok {
+		return
+	}
+	if _, err := os.Stat(imagePath); err != nil {
+		writeErrorResponse(w, http.StatusNotFound, "图片文件不存在", err)
+		return
+	}
+	defer h.cacheManager.Save()
+
+	hash, err := h.imageHash(imagePath, isFaceImage(imagePath))
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "无法计算图片的感知哈希", err)
+		return
+	}
+	h.writeSimilarImages(w, hash, maxDistance, imagePath)
+}
+
+// FindByImage 以图搜图：上传一张图片，查找角色卡或卡面与之相似的角色
+func (h *CardsHandler) FindByImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+	data, _, ok := readUploadedFile(w, r)
+	if !ok {
+		return
+	}
+	maxDistance, ok := parseMaxDistance(w, r.FormValue("maxDistance"))
+	if !ok {
+		return
+	}
+	img, _, err := imaging.Decode(data)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "上传的文件不是支持的图片格式", err)
+		return
+	}
+	defer h.cacheManager.Save()
+
+	h.writeSimilarImages(w, imaging.DHash(img), maxDistance, "")
+}
+
+// parseMaxDistance 解析相似距离参数，缺省时使用默认值，失败时直接写出错误响应
+func parseMaxDistance(w http.ResponseWriter, value string) (int, bool) {
+	if value == "" {
+		return defaultImageDistance, true
+	}
+	distance, err := strconv.Atoi(value)
+	if err != nil || distance < 0 || distance > 64 {
+		writeErrorResponse(w, http.StatusBadRequest, "相似距离应在 0 到 64 之间", err)
+		return 0, false
+	}
+	return distance, true
+}
+
+// writeSimilarImages 与角色库中的所有图片比较，写出相似结果，excludePath 为查询图片自身
+//
+// 感知哈希在扫描角色文件夹时已经算好，这里只读取；扫描后新增或修改的图片才在此时计算。
+func (h *CardsHandler) writeSimilarImages(w http.ResponseWriter, hash uint64, maxDistance int, excludePath string) {
+	scan, characters, err := h.collectCharacters(nil)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "无法获取卡片数据", err)
+		return
+	}
+
+	candidates := h.libraryImages(characters, scan.strays)
+	h.hashCandidates(candidates)
+
+	response := models.SimilarImagesResponse{
+		ImageHash:   imaging.FormatHash(hash),
+		MaxDistance: maxDistance,
+		Matches:     make([]models.ImageMatch, 0),
+		Characters:  make([]models.CharacterImageMatch, 0),
+	}
+	for _, candidate := range candidates {
+		if !candidate.ok || candidate.match.Path == excludePath {
+			continue
+		}
+		if distance := imaging.HashDistance(hash, candidate.hash); distance <= maxDistance {
+			match := candidate.match
+			match.Distance = distance
+			response.Matches = append(response.Matches, match)
+		}
+	}
+	sort.Slice(response.Matches, func(i, j int) bool {
+		if response.Matches[i].Distance != response.Matches[j].Distance {
+			return response.Matches[i].Distance < response.Matches[j].Distance
+		}
+		return response.Matches[i].Path < response.Matches[j].Path
+	})
+
+	// 匹配已按距离排序，每个角色第一次出现时即为最接近的一张
+	characterIndex := make(map[string]int)
+	for _, match := range response.Matches {
+		if match.Character == "" {
+			continue
+		}
+		index, found := characterIndex[match.FolderPath]
+		if !found {
+			index = len(response.Characters)
+			characterIndex[match.FolderPath] = index
+			response.Characters = append(response.Characters, models.CharacterImageMatch{
+				Category:   match.Category,
+				Character:  match.Character,
+				FolderPath: match.FolderPath,
+				Distance:   match.Distance,
+			})
+		}
+		response.Characters[index].Matches = append(response.Characters[index].Matches, match)
+	}
+
+	slog.Info("🖼️ 相似图片查询完成", "图片数", len(candidates), "匹配", len(response.Matches), "角色", len(response.Characters))
+	writeSuccessResponse(w, fmt.Sprintf("找到 %d 个角色的 %d 张相似图片", len(response.Characters), len(response.Matches)), response)
+}
+
+// libraryImages 列出角色库中所有角色卡版本、待整理卡片和卡面图片
+func (h *CardsHandler) libraryImages(characters []listedCharacter, strays []models.StrayCard) []imageCandidate {
+	candidates := make([]imageCandidate, 0)
+	for _, listed := range characters {
+		character := listed.character
+		base := models.ImageMatch{Category: listed.category, Character: character.Name, FolderPath: character.FolderPath}
+		for _, version := range character.Versions {
+			match := base
+			match.Path, match.Kind = version.Path, imageKindCard
+			candidates = append(candidates, imageCandidate{match: match})
+		}
+		if !character.HasFaceFolder {
+			continue
+		}
+		faceDir := filepath.Join(character.FolderPath, "卡面")
+		files, err := os.ReadDir(faceDir)
+		if err != nil {
+			slog.Warn("📂 无法读取卡面目录", "路径", faceDir, "error", err)
+			continue
+		}
+		for _, file := range files {
+			if !file.IsDir() && isImageFile(file.Name()) {
+				match := base
+				match.Path, match.Kind = filepath.Join(faceDir, file.Name()), imageKindFace
+				candidates = append(candidates, imageCandidate{match: match})
+			}
+		}
+	}
+	for _, stray := range strays {
+		candidates = append(candidates, imageCandidate{match: models.ImageMatch{
+			Path:       stray.Path,
+			Kind:       imageKindCard,
+			FolderPath: filepath.Dir(stray.Path),
+		}})
+	}
+	return candidates
+}
+
+// hashCandidates 并行读取所有候选图片的感知哈希，无法解码的图片在计算时已记录日志，这里直接跳过
+func (h *CardsHandler) hashCandidates(candidates []imageCandidate) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				candidate := &candidates[index]
+				hash, err := h.imageHash(candidate.match.Path, candidate.match.Kind == imageKindFace)
+				if err != nil {
+					continue
+				}
+				candidate.hash, candidate.ok = hash, true
+			}
+		}()
+	}
+	for i := range candidates {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// imageHash 获取角色卡头像或卡面图片的感知哈希
+//
+// 角色卡头像和卡面的哈希都保存在元数据缓存中，以路径和修改时间为准，文件修改过时重新计算。
+func (h *CardsHandler) imageHash(filePath string, isFace bool) (uint64, error) {
+	var value string
+	if isFace {
+		stats, err := os.Stat(filePath)
+		if err != nil {
+			return 0, err
+		}
+		value = h.faceHash(filePath, stats.ModTime().Format(time.RFC3339Nano))
+	} else {
+		metadata, err := h.getCardMetadata(filePath)
+		if err != nil {
+			return 0, err
+		}
+		value = metadata.ImageHash
+	}
+	if value == "" {
+		return 0, errors.New("图片无法解码")
+	}
+	return imaging.ParseHash(value)
+}
+
+// hashFaces 计算卡面目录中新增或修改过的图片的感知哈希
+func (h *CardsHandler) hashFaces(faceDir string) {
+	files, err := os.ReadDir(faceDir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if file.IsDir() || !isImageFile(file.Name()) {
+			continue
+		}
+		if info, err := file.Info(); err == nil {
+			h.faceHash(filepath.Join(faceDir, file.Name()), info.ModTime().Format(time.RFC3339Nano))
+		}
+	}
+}
+
+// faceHash 返回卡面图片的感知哈希，缓存中没有该修改时间的条目时计算并缓存，无法解码时为空串
+func (h *CardsHandler) faceHash(filePath, mtime string) string {
+	if entry, found := h.cacheManager.Get(filePath); found && entry.Mtime == mtime {
+		return entry.ImageHash
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return ""
+	}
+	hash := hashImage(data, filePath)
+	h.cacheManager.Set(filePath, cache.Entry{Mtime: mtime, ImageHash: hash})
+	return hash
+}
+
+// avatarHash 计算版本文件头像的感知哈希，无法读取或解码时为空串
+func avatarHash(filePath string) string {
+	data, err := cardfile.Avatar(filePath)
+	if err != nil {
+		slog.Warn("读取头像失败", "文件", filepath.Base(filePath), "error", err)
+		return ""
+	}
+	return hashImage(data, filePath)
+}
+
+// hashImage 解码图片并计算格式化后的感知哈希，无法解码时为空串
+func hashImage(data []byte, filePath string) string {
+	img, _, err := imaging.Decode(data)
+	if err != nil {
+		slog.Warn("计算感知哈希失败", "文件", filepath.Base(filePath), "error", err)
+		return ""
+	}
+	return imaging.FormatHash(imaging.DHash(img))
+}
+
+// isFaceImage 判断文件是否位于角色的卡面目录中
+func isFaceImage(filePath string) bool {
+	return filepath.Base(filepath.Dir(filePath)) == "卡面"
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/cache"
+	"card-manager/internal/pkg/journal"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultJournalLimit 操作日志默认返回的条数
+const defaultJournalLimit = 50
+
+// 操作日志中的操作类型
+const (
+	opDownloadCard      = "download-card"
+	opDeleteVersion     = "delete-version"
+	opDeleteCharacter   = "delete-character"
+	opMoveCharacter     = "move-character"
+	opOrganizeStray     = "organize-stray"
+	opDeleteStray       = "delete-stray"
+	opMergeJsonToPng    = "merge-json-to-png"
+	opConvertCard       = "convert-card"
+	opExtractCardJson   = "extract-card-json"
+	opReplaceAvatar     = "replace-avatar"
+	opEditCard          = "edit-card"
+	opAttachLorebook    = "attach-lorebook"
+	opExportLorebook    = "export-lorebook"
+	opResolveDuplicates = "resolve-duplicates"
+	opRestoreTrash      = "restore-trash"
+	opRenameCharacter   = "rename-character"
+	opCreateCategory    = "create-category"
+	opRenameCategory    = "rename-category"
+	opMergeCategories   = "merge-categories"
+	opDeleteCategory    = "delete-category"
+	opVersionNote       = "version-note"
+	opCurrentVersion    = "current-version"
+	opCharacterState    = "character-state"
+	opCharacterTags     = "character-tags"
+	opCreateTag         = "create-tag"
+	opRenameTag         = "rename-tag"
+	opMergeTags         = "merge-tags"
+	opDeleteTag         = "delete-tag"
+	opSaveCollection    = "save-collection"
+	opRenameCollection  = "rename-collection"
+	opDeleteCollection  = "delete-collection"
+	opImportCollections = "import-collections"
+	opSaveNote          = "save-note"
+)
+
+// JournalHandler 处理操作日志和撤销相关的API请求
+type JournalHandler struct {
+	journal      *journal.Journal
+	cacheManager *cache.Manager
+}
+
+// NewJournalHandler 创建新的操作日志处理器
+func NewJournalHandler(operations *journal.Journal, cacheManager *cache.Manager) *JournalHandler {
+	return &JournalHandler{
+		journal:      operations,
+		cacheManager: cacheManager,
+	}
+}
+
+// ListJournal 列出最近的操作，最新的在前
+func (h *JournalHandler) ListJournal(w http.ResponseWriter, r *http.Request) {
+	limit := defaultJournalLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "limit 参数无效", err)
+			return
+		}
+		limit = parsed
+	}
+
+	ops, err := h.journal.List(limit)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "读取操作日志失败", err)
+		return
+	}
+	writeSuccessResponse(w, fmt.Sprintf("共 %d 条操作记录", len(ops)), toJournalOperations(ops))
+}
+
+// Undo 撤销指定的操作，或从最新的操作开始撤销若干个
+func (h *JournalHandler) Undo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.UndoRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+
+	var undone []journal.Operation
+	var err error
+	if req.ID != "" {
+		var op *journal.Operation
+		if op, err = h.journal.Undo(req.ID); err == nil {
+			undone = append(undone, *op)
+		}
+	} else {
+		count := req.Count
+		if count <= 0 {
+			count = 1
+		}
+		undone, err = h.journal.UndoLast(count)
+	}
+	h.rekeyUndone(undone)
+
+	data := map[string]interface{}{"undone": toJournalOperations(undone)}
+	if err != nil {
+		var undoErr *journal.UndoError
+		switch {
+		case errors.Is(err, journal.ErrNotFound):
+			writeErrorResponseWithData(w, http.StatusNotFound, err.Error(), err, data)
+		case errors.Is(err, journal.ErrAlreadyUndone), errors.As(err, &undoErr):
+			writeErrorResponseWithData(w, http.StatusConflict, err.Error(), err, data)
+		default:
+			writeErrorResponseWithData(w, http.StatusInternalServerError, "撤销失败: "+err.Error(), err, data)
+		}
+		return
+	}
+	if len(undone) == 0 {
+		writeSuccessResponse(w, "没有可以撤销的操作", data)
+		return
+	}
+
+	for _, op := range undone {
+		slog.Info("↩️ 操作已撤销", "操作", op.Description)
+	}
+	writeSuccessResponse(w, fmt.Sprintf("已撤销 %d 个操作", len(undone)), data)
+}
+
+// rekeyUndone 把撤销时移回原位置的文件和文件夹的缓存条目迁移回原路径
+func (h *JournalHandler) rekeyUndone(ops []journal.Operation) {
+	rekeyed := 0
+	for _, op := range ops {
+		for i := len(op.Steps) - 1; i >= 0; i-- {
+			if step := op.Steps[i]; step.Kind == journal.StepMove {
+				rekeyed += h.cacheManager.Rekey(step.To, step.From)
+			}
+		}
+	}
+	if rekeyed > 0 {
+		h.cacheManager.Save()
+	}
+}
+
+// toJournalOperations 转换为响应模型，不暴露回收站条目和指纹等内部信息
+func toJournalOperations(ops []journal.Operation) []models.JournalOperation {
+	result := make([]models.JournalOperation, 0, len(ops))
+	for _, op := range ops {
+		item := models.JournalOperation{
+			ID:          op.ID,
+			Type:        op.Type,
+			Description: op.Description,
+			Time:        op.Time.Format(time.RFC3339),
+			Steps:       make([]models.JournalStep, 0, len(op.Steps)),
+			Undone:      op.UndoneAt != nil,
+		}
+		for _, step := range op.Steps {
+			item.Steps = append(item.Steps, models.JournalStep{Kind: step.Kind, Path: step.Path, From: step.From, To: step.To})
+		}
+		result = append(result, item)
+	}
+	return result
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/jsonfile"
+	"card-manager/internal/pkg/localization"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultListLimit 角色列表每页默认的角色数
+	defaultListLimit = 50
+	// maxListLimit 角色列表每页最多的角色数
+	maxListLimit = 1000
+)
+
+// characterSorts /api/characters 的 sort 参数可用的排序方式，返回负数表示升序时 a 在 b 之前
+var characterSorts = map[string]func(a, b *models.CharacterSummary) int{
+	"name": func(a, b *models.CharacterSummary) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	},
+	"mtime": func(a, b *models.CharacterSummary) int {
+		return parseMtime(a.LatestMtime).Compare(parseMtime(b.LatestMtime))
+	},
+	"versions": func(a, b *models.CharacterSummary) int {
+		return a.VersionCount - b.VersionCount
+	},
+	"import": func(a, b *models.CharacterSummary) int {
+		return importRank(a.ImportInfo) - importRank(b.ImportInfo)
+	},
+	"rating": func(a, b *models.CharacterSummary) int {
+		return a.Rating - b.Rating
+	},
+	"favorite": func(a, b *models.CharacterSummary) int {
+		return boolRank(a.Favorite) - boolRank(b.Favorite)
+	},
+	"status": func(a, b *models.CharacterSummary) int {
+		return statusRank(a.Status) - statusRank(b.Status)
+	},
+}
+
+// characterListing 缓存角色列表中每个角色文件夹的读取结果
+//
+// 以文件夹中各文件和子文件夹的名称、大小和修改时间，以及标签登记表、酒馆导入状态、本地化资源目录
+// 和元数据缓存的状态为戳记，戳记不变时不再重新读取文件夹。
+type characterListing struct {
+	mutex   sync.Mutex
+	entries map[string]listingEntry
+}
+
+// listingEntry 角色文件夹的读取结果及读取时的戳记
+type listingEntry struct {
+	stamp     string
+	character *models.Character
+}
+
+// get 返回戳记相同的读取结果
+func (l *characterListing) get(folderPath, stamp string) (*models.Character, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	entry, found := l.entries[folderPath]
+	if !found || entry.stamp != stamp {
+		return nil, false
+	}
+	return entry.character, true
+}
+
+// set 记录角色文件夹的读取结果
+func (l *characterListing) set(folderPath, stamp string, character *models.Character) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries[folderPath] = listingEntry{stamp: stamp, character: character}
+}
+
+// prune 丢弃已不在角色库中的文件夹
+func (l *characterListing) prune(folders []characterFolder) {
+	present := make(map[string]bool, len(folders))
+	for _, folder := range folders {
+		present[folder.path] = true
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for folderPath := range l.entries {
+		if !present[folderPath] {
+			delete(l.entries, folderPath)
+		}
+	}
+}
+
+// listedCharacter 读取角色文件夹，戳记没有变化时使用上次的结果
+//
+// external 为 listingStamp 返回的外部状态戳记。
+func (h *CardsHandler) listedCharacter(folderPath, external string) *models.Character {
+	stamp, err := folderStamp(folderPath)
+	if err != nil {
+		return h.processCharacterDirectory(folderPath)
+	}
+	stamp += external
+	if character, found := h.listing.get(folderPath, stamp); found {
+		return character
+	}
+	character := h.processCharacterDirectory(folderPath)
+	h.listing.set(folderPath, stamp, character)
+	return character
+}
+
+// listingStamp 角色文件夹之外影响角色信息的状态：标签登记表、酒馆导入状态、本地化资源目录和元数据缓存
+func (h *CardsHandler) listingStamp() string {
+	scanned := 0
+	if h.tavernScanner != nil {
+		scanned = h.tavernScanner.Generation()
+	}
+	nikoPath := localization.NewService(h.config.TavernPublicPath, h.config.Proxy).NikoPath()
+	return fmt.Sprintf("|%s|%d|%s|%d", jsonfile.Stamp(h.tags.Path()), scanned, jsonfile.Stamp(nikoPath), h.cacheManager.Generation())
+}
+
+// folderStamp 文件夹中各文件和子文件夹的名称、大小和修改时间
+//
+// 子文件夹的修改时间在其中增删文件时改变，卡面的增减因此也会让戳记变化。
+func folderStamp(folderPath string) (string, error) {
+	entries, err := os.ReadDir(folderPath)
+	if err != nil {
+		return "", err
+	}
+	var stamp strings.Builder
+	for _, entry := range entries {
+		stamp.WriteString(entry.Name())
+		if info, err := entry.Info(); err == nil {
+			fmt.Fprintf(&stamp, ":%d-%d", info.Size(), info.ModTime().UnixNano())
+		}
+		stamp.WriteByte('\n')
+	}
+	return stamp.String(), nil
+}
+
+// descendingByDefault 未指定 order 时按降序排列的排序方式：最近修改的、版本多的、评分高的和收藏的在前
+var descendingByDefault = map[string]bool{"mtime": true, "versions": true, "rating": true, "favorite": true}
+
+// ListCharacters 分页获取角色列表，不含版本列表
+//
+// 支持 /api/cards 的全部筛选参数，以及 sort（name、mtime、versions、import、rating、favorite、status）、
+// order（asc、desc）、offset 和 limit。版本列表通过 /api/character 获取。
+func (h *CardsHandler) ListCharacters(w http.ResponseWriter, r *http.Request) {
+	defer h.cacheManager.Save()
+	defer h.index.Save()
+
+	query := r.URL.Query()
+	filter, err := h.requestFilter(query)
+	if err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	sortKey := query.Get("sort")
+	if sortKey == "" {
+		sortKey = "name"
+	}
+	compare, found := characterSorts[sortKey]
+	if !found {
+		writeErrorResponse(w, http.StatusBadRequest, "sort 参数无效: "+sortKey, nil)
+		return
+	}
+	descending := descendingByDefault[sortKey]
+	switch query.Get("order") {
+	case "":
+	case "asc":
+		descending = false
+	case "desc":
+		descending = true
+	default:
+		writeErrorResponse(w, http.StatusBadRequest, "order 参数无效，应为 asc 或 desc", nil)
+		return
+	}
+	offset, ok := intParam(w, query, "offset", 0, 0, -1)
+	if !ok {
+		return
+	}
+	limit, ok := intParam(w, query, "limit", defaultListLimit, 1, maxListLimit)
+	if !ok {
+		return
+	}
+
+	_, characters, err := h.collectCharacters(filter)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "获取角色列表失败", err)
+		return
+	}
+	summaries := make([]models.CharacterSummary, 0, len(characters))
+	for _, listed := range characters {
+		summaries = append(summaries, summarizeCharacter(listed))
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		result := compare(&summaries[i], &summaries[j])
+		if descending {
+			result = -result
+		}
+		if result != 0 {
+			return result < 0
+		}
+		// 并发读取的顺序不固定，同值时按名称和路径排序保证分页稳定
+		if byName := characterSorts["name"](&summaries[i], &summaries[j]); byName != 0 {
+			return byName < 0
+		}
+		return summaries[i].FolderPath < summaries[j].FolderPath
+	})
+
+	response := models.CharacterListResponse{
+		Total:      len(summaries),
+		Offset:     offset,
+		Limit:      limit,
+		Characters: make([]models.CharacterSummary, 0),
+	}
+	if offset < len(summaries) {
+		end := min(offset+limit, len(summaries))
+		response.Characters = summaries[offset:end]
+		if end < len(summaries) {
+			response.NextOffset = &end
+		}
+	}
+	writeSuccessResponse(w, fmt.Sprintf("共 %d 个角色", response.Total), response)
+}
+
+// GetCharacter 获取单个角色的完整信息，包括所有版本
+func (h *CardsHandler) GetCharacter(w http.ResponseWriter, r *http.Request) {
+	defer h.cacheManager.Save()
+
+	folderPath, ok := h.characterFolder(w, r.URL.Query().Get("folderPath"))
+	if !ok {
+		return
+	}
+	character := h.processCharacterDirectory(folderPath)
+	if character == nil {
+		writeErrorResponse(w, http.StatusNotFound, "角色文件夹中没有角色卡", nil)
+		return
+	}
+	writeSuccessResponse(w, "获取角色成功", character)
+}
+
+// summarizeCharacter 生成不含版本列表的角色摘要
+func summarizeCharacter(listed listedCharacter) models.CharacterSummary {
+	character := listed.character
+	summary := models.CharacterSummary{
+		Name:               character.Name,
+		InternalName:       character.InternalName,
+		FolderPath:         character.FolderPath,
+		Category:           listed.category,
+		LatestVersionPath:  character.LatestVersionPath,
+		VersionCount:       character.VersionCount,
+		ImportInfo:         character.ImportInfo,
+		HasNote:            character.HasNote,
+		HasFaceFolder:      character.HasFaceFolder,
+		LocalizationNeeded: character.LocalizationNeeded,
+		IsLocalized:        character.IsLocalized,
+		CurrentPinned:      character.CurrentPinned,
+		Creator:            character.Creator,
+		Tags:               character.Tags,
+		UserTags:           character.UserTags,
+		Favorite:           character.Favorite,
+		Rating:             character.Rating,
+		Status:             character.Status,
+	}
+	if latest := latestModified(character); !latest.IsZero() {
+		summary.LatestMtime = latest.Format(time.RFC3339Nano)
+	}
+	return summary
+}
+
+// importRank 导入状态的排序值：未导入、导入的不是当前版本、已导入当前版本
+func importRank(info models.ImportInfo) int {
+	switch {
+	case !info.IsImported:
+		return 0
+	case !info.IsLatestImported:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// boolRank 布尔值的排序值，true 排在 false 之后
+func boolRank(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// parseMtime 解析修改时间，无法解析时返回零值
+func parseMtime(value string) time.Time {
+	parsed, _ := time.Parse(time.RFC3339Nano, value)
+	return parsed
+}
+
+// intParam 解析整数查询参数，为空时返回默认值，maxValue 小于 0 表示没有上限；无效时直接写出错误响应
+func intParam(w http.ResponseWriter, query url.Values, name string, defaultValue, minValue, maxValue int) (int, bool) {
+	value := query.Get(name)
+	if value == "" {
+		return defaultValue, true
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < minValue || (maxValue >= 0 && parsed > maxValue) {
+		message := fmt.Sprintf("%s 参数无效，应为不小于 %d 的整数", name, minValue)
+		if maxValue >= 0 {
+			message = fmt.Sprintf("%s 参数无效，应为 %d 到 %d 之间的整数", name, minValue, maxValue)
+		}
+		writeErrorResponse(w, http.StatusBadRequest, message, err)
+		return 0, false
+	}
+	return parsed, true
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"card-manager/internal/config"
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/card"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/journal"
+	"card-manager/internal/pkg/trash"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LorebookHandler 处理角色卡内嵌世界书相关的API请求
+type LorebookHandler struct {
+	config  *config.Config
+	trash   *trash.Bin
+	journal *journal.Journal
+}
+
+// NewLorebookHandler 创建新的世界书处理器
+func NewLorebookHandler(config *config.Config, trashBin *trash.Bin, operations *journal.Journal) *LorebookHandler {
+	return &LorebookHandler{
+		config:  config,
+		trash:   trashBin,
+		journal: operations,
+	}
+}
+
+// GetLorebook 列出版本中内嵌世界书的条目
+func (h *LorebookHandler) GetLorebook(w http.ResponseWriter, r *http.Request) {
+	versionPath := r.URL.Query().Get("path")
+	if versionPath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少路径参数", nil)
+		return
+	}
+
+	parsed, ok := loadVersionCard(w, versionPath)
+	if !ok {
+		return
+	}
+
+	response := models.LorebookResponse{Entries: make([]models.LorebookEntry, 0)}
+	book := parsed.Data.CharacterBook
+	if book == nil {
+		writeSuccessResponse(w, "该角色卡没有内嵌世界书", response)
+		return
+	}
+
+	response.HasLorebook = true
+	response.Name = book.Name
+	response.Description = book.Description
+	for i, entry := range book.Entries {
+		response.Entries = append(response.Entries, lorebookEntry(i, entry))
+	}
+
+	writeSuccessResponse(w, fmt.Sprintf("找到 %d 个世界书条目", len(response.Entries)), response)
+}
+
+// ExportLorebook 将内嵌世界书导出为 SillyTavern World Info JSON
+//
+// GET 以附件形式直接下载，POST 将文件保存到版本文件旁边。
+func (h *LorebookHandler) ExportLorebook(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		h.downloadLorebook(w, r)
+	} else if r.Method == http.MethodPost {
+		h.saveLorebook(w, r)
+	} else {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+	}
+}
+
+// downloadLorebook 以附件形式返回 World Info JSON
+func (h *LorebookHandler) downloadLorebook(w http.ResponseWriter, r *http.Request) {
+	versionPath := r.URL.Query().Get("path")
+	if versionPath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少路径参数", nil)
+		return
+	}
+
+	data, ok := h.worldInfoJSON(w, versionPath)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": lorebookFileName(versionPath),
+	}))
+	w.Write(data)
+}
+
+// saveLorebook 将 World Info JSON 保存到版本文件所在目录
+func (h *LorebookHandler) saveLorebook(w http.ResponseWriter, r *http.Request) {
+	var req models.ExportLorebookRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	if req.Path == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少路径参数", nil)
+		return
+	}
+	if !withinRoot(h.config.CharactersRootPath, req.Path) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+
+	data, ok := h.worldInfoJSON(w, req.Path)
+	if !ok {
+		return
+	}
+
+	folderPath := filepath.Dir(req.Path)
+	outputPath := filepath.Join(folderPath, lorebookFileName(req.Path))
+	if !req.Overwrite {
+		var err error
+		outputPath, err = uniqueFilePath(folderPath, lorebookFileName(req.Path))
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, err.Error(), err)
+			return
+		}
+	}
+	backupID, err := backupBeforeWrite(h.trash, outputPath)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error(), err)
+		return
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "保存世界书失败", err)
+		return
+	}
+	h.journal.Record(opExportLorebook, "导出世界书 "+filepath.Base(outputPath), writtenStep(outputPath, backupID))
+
+	slog.Info("📚 世界书已导出", "源文件", filepath.Base(req.Path), "新文件", filepath.Base(outputPath))
+	writeSuccessResponse(w, "导出成功！世界书已保存为: "+filepath.Base(outputPath), map[string]string{
+		"fileName": filepath.Base(outputPath),
+		"path":     outputPath,
+	})
+}
+
+// worldInfoJSON 读取版本中的世界书并转换为格式化的 World Info JSON，失败时直接写出错误响应
+func (h *LorebookHandler) worldInfoJSON(w http.ResponseWriter, versionPath string) ([]byte, bool) {
+	parsed, ok := loadVersionCard(w, versionPath)
+	if !ok {
+		return nil, false
+	}
+	book := parsed.Data.CharacterBook
+	if book == nil {
+		writeErrorResponse(w, http.StatusNotFound, "该角色卡没有内嵌世界书", nil)
+		return nil, false
+	}
+
+	data, err := json.MarshalIndent(book.WorldInfo(), "", "  ")
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "序列化世界书失败", err)
+		return nil, false
+	}
+	return append(data, '\n'), true
+}
+
+// AttachLorebook 将世界书文件写入角色卡，替换原有的内嵌世界书并保存为新版本
+//
+// 世界书可以是 SillyTavern World Info 格式，也可以是 character_book 格式。
+func (h *LorebookHandler) AttachLorebook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.AttachLorebookRequest
+	var worldData []byte
+	if isMultipartRequest(r) {
+		var ok bool
+		if worldData, _, ok = readUploadedFile(w, r); !ok {
+			return
+		}
+		req.VersionPath = r.FormValue("versionPath")
+		req.OutputFileName = r.FormValue("outputFileName")
+	} else {
+		if err := decodeJSONRequest(r, &req); err != nil {
+			handleAppError(w, err.(*models.AppError))
+			return
+		}
+		if req.WorldPath == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "缺少世界书文件路径", nil)
+			return
+		}
+		if !withinRoot(h.config.CharactersRootPath, req.WorldPath) {
+			writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+			return
+		}
+		var err error
+		if worldData, err = os.ReadFile(req.WorldPath); err != nil {
+			writeErrorResponse(w, http.StatusNotFound, "世界书文件不存在", err)
+			return
+		}
+	}
+
+	if req.VersionPath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少版本文件路径", nil)
+		return
+	}
+	if !withinRoot(h.config.CharactersRootPath, req.VersionPath) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+
+	book, err := card.ParseLorebook(worldData)
+	if err != nil {
+		writeErrorResponseWithData(w, http.StatusBadRequest, "世界书文件格式无效", err, map[string]interface{}{
+			"fieldErrors": card.FieldErrors(err),
+		})
+		return
+	}
+
+	parsed, ok := loadVersionCard(w, req.VersionPath)
+	if !ok {
+		return
+	}
+	parsed.Data.CharacterBook = book
+	if err := parsed.Validate(); err != nil {
+		writeErrorResponseWithData(w, http.StatusBadRequest, "写入世界书后的角色卡校验失败", err, map[string]interface{}{
+			"fieldErrors": card.FieldErrors(err),
+		})
+		return
+	}
+
+	extension := filepath.Ext(req.VersionPath)
+	outputFileName := filepath.Base(req.OutputFileName)
+	if req.OutputFileName == "" {
+		outputFileName = strings.TrimSuffix(filepath.Base(req.VersionPath), extension) + "_lorebook"
+	}
+	if !strings.EqualFold(filepath.Ext(outputFileName), extension) {
+		outputFileName += extension
+	}
+	outputPath, err := uniqueFilePath(filepath.Dir(req.VersionPath), outputFileName)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error(), err)
+		return
+	}
+
+	if err := cardfile.Save(req.VersionPath, outputPath, parsed); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("写入世界书失败: %v", err), err)
+		return
+	}
+	h.journal.Record(opAttachLorebook, "写入世界书并保存为 "+filepath.Base(outputPath), journal.Created(outputPath))
+
+	slog.Info("📚 世界书已写入角色卡", "版本", filepath.Base(req.VersionPath), "条目数", len(book.Entries), "新文件", filepath.Base(outputPath))
+	writeSuccessResponse(w, fmt.Sprintf("已写入 %d 个世界书条目，新版本已保存为: %s", len(book.Entries), filepath.Base(outputPath)), map[string]string{
+		"fileName": filepath.Base(outputPath),
+		"path":     outputPath,
+	})
+}
+
+// lorebookFileName 返回版本文件对应的世界书文件名
+func lorebookFileName(versionPath string) string {
+	return strings.TrimSuffix(filepath.Base(versionPath), filepath.Ext(versionPath)) + "_world.json"
+}
+
+// lorebookEntry 生成世界书条目摘要
+func lorebookEntry(index int, entry card.BookEntry) models.LorebookEntry {
+	keys := entry.Keys
+	if keys == nil {
+		keys = []string{}
+	}
+	return models.LorebookEntry{
+		Index:          index,
+		Name:           entry.Name,
+		Comment:        entry.Comment,
+		Keys:           keys,
+		SecondaryKeys:  entry.SecondaryKeys,
+		Enabled:        entry.Enabled,
+		Constant:       entry.Constant != nil && *entry.Constant,
+		Position:       entry.Position,
+		InsertionOrder: entry.InsertionOrder,
+	}
+}
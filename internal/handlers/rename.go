@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/journal"
+	"card-manager/internal/pkg/localization"
+	"card-manager/internal/pkg/sidecar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RenameCharacter 重命名角色文件夹，迁移缓存条目并保留与本地化资源的关联
+//
+// RenameInternal 为 true 时还会把当前版本中的角色名改为新名称，另存为新版本。
+func (h *CardsHandler) RenameCharacter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.RenameCharacterRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	if req.FolderPath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少角色文件夹路径", nil)
+		return
+	}
+	rootPath := filepath.Clean(h.config.CharactersRootPath)
+	folderPath := filepath.Clean(req.FolderPath)
+	if !withinRoot(rootPath, folderPath) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+	if folderDepth(rootPath, folderPath) < 2 {
+		writeErrorResponse(w, http.StatusBadRequest, "只能重命名分类下的角色文件夹", nil)
+		return
+	}
+	info, err := os.Stat(folderPath)
+	if err != nil || !info.IsDir() {
+		writeErrorResponse(w, http.StatusNotFound, "角色文件夹不存在", err)
+		return
+	}
+	if classifyFolder(folderPath) == folderCategory {
+		writeErrorResponse(w, http.StatusBadRequest, "这是一个分类，请通过分类管理重命名", nil)
+		return
+	}
+
+	newName := strings.TrimSpace(req.NewName)
+	if reason := validateFolderName(newName); reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	oldName := filepath.Base(folderPath)
+	newFolderPath := filepath.Join(filepath.Dir(folderPath), newName)
+	renameFolder := newName != oldName
+	if renameFolder {
+		// 只改大小写时在不区分大小写的文件系统上会找到自身
+		if existing, err := os.Stat(newFolderPath); err == nil && !os.SameFile(info, existing) {
+			writeErrorResponseWithData(w, http.StatusConflict, fmt.Sprintf("分类中已存在名为 %s 的文件夹", newName), nil, map[string]string{
+				"conflictPath": newFolderPath,
+			})
+			return
+		}
+	}
+
+	character := h.processCharacterDirectory(folderPath)
+	if req.RenameInternal && character == nil {
+		writeErrorResponse(w, http.StatusBadRequest, "角色文件夹中没有角色卡，无法修改角色卡内的名称", nil)
+		return
+	}
+	renameInternal := req.RenameInternal && character.InternalName != newName
+	if !renameFolder && !renameInternal {
+		writeErrorResponse(w, http.StatusBadRequest, "新名称与原名称相同", nil)
+		return
+	}
+	defer h.cacheManager.Save()
+
+	if renameFolder {
+		if err := os.Rename(folderPath, newFolderPath); err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "重命名角色失败", err)
+			return
+		}
+		rekeyed := h.cacheManager.Rekey(folderPath, newFolderPath)
+		slog.Info("✏️ 角色已重命名", "原名称", oldName, "新名称", newName, "缓存条目", rekeyed)
+	}
+
+	// 附属数据随文件夹一起移动，在移动后备份一次，所有修改完成后只生成一个撤销步骤
+	keepLocalized := character != nil && character.IsLocalized && (renameInternal || character.InternalName == "")
+	sidecarPath := filepath.Join(newFolderPath, sidecar.FileName)
+	sidecarBackupID := ""
+	if keepLocalized || (renameInternal && character.CurrentPinned) {
+		if sidecarBackupID, err = backupBeforeWrite(h.trash, sidecarPath); err != nil {
+			h.recordRename(folderPath, newFolderPath, "", renameFolder)
+			writeErrorResponse(w, http.StatusInternalServerError, "备份角色附属数据失败", err)
+			return
+		}
+	}
+
+	// 本地化资源以角色卡内的名称命名，卡内没有名称时以文件夹名命名，名称改变前记下原来的资源
+	if keepLocalized {
+		if err := h.keepLocalizedName(newFolderPath, oldName, character); err != nil {
+			slog.Warn("记录本地化资源名称失败", "角色", oldName, "error", err)
+		}
+	}
+
+	newVersionPath := ""
+	if renameInternal {
+		currentPath := filepath.Join(newFolderPath, filepath.Base(character.LatestVersionPath))
+		newVersionPath, err = h.saveRenamedVersion(newFolderPath, currentPath, newName, character.CurrentPinned)
+		if err != nil {
+			h.recordRename(folderPath, newFolderPath, "", renameFolder, resultStep(sidecarPath, sidecarBackupID))
+			writeErrorResponse(w, http.StatusInternalServerError, "保存修改了角色名的新版本失败", err)
+			return
+		}
+		slog.Info("✏️ 角色卡内的名称已修改", "新名称", newName, "新文件", filepath.Base(newVersionPath))
+	}
+	h.recordRename(folderPath, newFolderPath, newVersionPath, renameFolder, resultStep(sidecarPath, sidecarBackupID))
+
+	message := fmt.Sprintf("角色 %s 已重命名为 %s", oldName, newName)
+	if !renameFolder {
+		message = "角色卡内的名称已修改为 " + newName
+	}
+	if newVersionPath != "" {
+		message += "，新版本已保存为: " + filepath.Base(newVersionPath)
+	}
+	writeSuccessResponse(w, message, h.processCharacterDirectory(newFolderPath))
+}
+
+// recordRename 将重命名记入操作日志
+//
+// 文件夹的指纹包含新版本，所以两个步骤都在全部完成后生成；撤销时先还原附属数据、
+// 移走新版本，再改回文件夹名。
+func (h *CardsHandler) recordRename(oldPath, newPath, newVersionPath string, renameFolder bool, sidecarSteps ...journal.Step) {
+	steps := make([]journal.Step, 0, 3)
+	if renameFolder {
+		steps = append(steps, journal.Moved(oldPath, newPath))
+	}
+	if newVersionPath != "" {
+		steps = append(steps, journal.Created(newVersionPath))
+	}
+	steps = append(steps, sidecarSteps...)
+	h.journal.Record(opRenameCharacter, fmt.Sprintf("重命名角色 %s 为 %s", filepath.Base(oldPath), filepath.Base(newPath)), steps...)
+}
+
+// keepLocalizedName 在附属数据中记下角色当前的本地化资源文件夹，已有记录时保持不变
+//
+// 卡内没有名称时资源以原文件夹名 oldName 命名。
+func (h *CardsHandler) keepLocalizedName(folderPath, oldName string, character *models.Character) error {
+	name := character.InternalName
+	if name == "" {
+		name = oldName
+	}
+	folderName, err := localization.NewService(h.config.TavernPublicPath, h.config.Proxy).LocalizedFolder(name)
+	if err != nil || folderName == "" {
+		return err
+	}
+	return sidecar.Update(folderPath, func(s *sidecar.Sidecar) error {
+		if s.LocalizedName == "" {
+			s.LocalizedName = folderName
+		}
+		return nil
+	})
+}
+
+// saveRenamedVersion 把版本中的角色名改为 name 并另存为新版本，当前版本是手动指定的时改为指定新版本
+func (h *CardsHandler) saveRenamedVersion(folderPath, versionPath, name string, pinned bool) (string, error) {
+	parsed, err := cardfile.Load(versionPath)
+	if err != nil {
+		return "", err
+	}
+	applyCardUpdates(parsed, models.CardFieldUpdates{Name: &name})
+
+	outputPath, err := uniqueFilePath(folderPath, name+filepath.Ext(versionPath))
+	if err != nil {
+		return "", err
+	}
+	if err := cardfile.Save(versionPath, outputPath, parsed); err != nil {
+		return "", err
+	}
+	if !pinned {
+		return outputPath, nil
+	}
+	metadata, err := h.getCardMetadata(outputPath)
+	if err != nil {
+		return outputPath, nil
+	}
+	err = sidecar.Update(folderPath, func(s *sidecar.Sidecar) error {
+		s.CurrentVersion = &sidecar.VersionKey{Hash: metadata.Hash, FileName: filepath.Base(outputPath)}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("指定新版本为当前版本失败", "文件", outputPath, "error", err)
+	}
+	return outputPath, nil
+}
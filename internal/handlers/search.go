@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/card"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/fulltext"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultSearchLimit 全文搜索默认返回的角色数
+	defaultSearchLimit = 20
+	// maxSnippets 每个角色最多返回的摘要数
+	maxSnippets = 3
+)
+
+// SearchContent 在角色卡内容和角色备注中全文搜索，按相关度返回角色及命中的摘要
+//
+// 搜索前会检查所有角色文件夹，只重新索引修改时间变化的文件。
+func (h *CardsHandler) SearchContent(w http.ResponseWriter, r *http.Request) {
+	defer h.cacheManager.Save()
+	defer h.index.Save()
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(fulltext.QueryTerms(query)) == 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少搜索内容", nil)
+		return
+	}
+	limit := defaultSearchLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "limit 参数无效", err)
+			return
+		}
+		limit = parsed
+	}
+
+	scan, err := scanLibrary(h.config.CharactersRootPath)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "搜索失败", err)
+		return
+	}
+	categories := make(map[string]string, len(scan.characters))
+	for _, folder := range scan.characters {
+		categories[folder.path] = folder.category
+	}
+	h.refreshIndex(scan.characters)
+
+	results := h.index.Search(query, func(path string) string {
+		folderPath := filepath.Dir(path)
+		if _, found := categories[folderPath]; found {
+			return folderPath
+		}
+		return ""
+	})
+	response := models.SearchResponse{Query: query, Total: len(results), Results: make([]models.SearchResult, 0)}
+	for _, result := range results {
+		if len(response.Results) >= limit {
+			break
+		}
+		character := h.processCharacterDirectory(result.Key)
+		if character == nil {
+			continue
+		}
+		item := models.SearchResult{
+			Character: *character,
+			Category:  categories[result.Key],
+			Score:     result.Score,
+			Snippets:  make([]models.SearchSnippet, 0),
+		}
+		// 各版本中相同的内容只保留一段摘要
+		seen := make(map[string]bool)
+		for _, path := range result.Paths {
+			for _, snippet := range fulltext.Snippets(h.index.Fields(path), query, maxSnippets) {
+				if seen[snippet.Field+snippet.HTML] || len(item.Snippets) >= maxSnippets {
+					continue
+				}
+				seen[snippet.Field+snippet.HTML] = true
+				item.Snippets = append(item.Snippets, models.SearchSnippet{FileName: filepath.Base(path), Field: snippet.Field, HTML: snippet.HTML})
+			}
+			if len(item.Snippets) >= maxSnippets {
+				break
+			}
+		}
+		response.Results = append(response.Results, item)
+	}
+
+	slog.Info("🔎 全文搜索完成", "搜索", query, "命中", len(results))
+	writeSuccessResponse(w, fmt.Sprintf("找到 %d 个角色", len(results)), response)
+}
+
+// refreshIndex 重新索引角色文件夹中修改过的角色卡和备注，并移除已不存在的文件
+func (h *CardsHandler) refreshIndex(folders []characterFolder) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	for _, folder := range folders {
+		wg.Add(1)
+		go func(folderPath string) {
+			defer wg.Done()
+			entries, err := os.ReadDir(folderPath)
+			if err != nil {
+				return
+			}
+			for _, entry := range entries {
+				path := filepath.Join(folderPath, entry.Name())
+				switch {
+				case entry.IsDir():
+					continue
+				case cardfile.IsCardFile(entry.Name()):
+					h.getCardMetadata(path)
+				case strings.ToLower(entry.Name()) == "note.md":
+					h.indexNote(path)
+				default:
+					continue
+				}
+				mu.Lock()
+				seen[path] = true
+				mu.Unlock()
+			}
+		}(folder.path)
+	}
+	wg.Wait()
+
+	if removed := h.index.Prune(func(path string) bool { return seen[path] }); removed > 0 {
+		slog.Info("🔎 已从全文索引中移除不存在的文件", "数量", removed)
+	}
+}
+
+// indexCardFile 读取角色卡并加入全文索引，读取失败时以空内容索引，避免反复读取
+func (h *CardsHandler) indexCardFile(filePath, mtime string) {
+	parsed, err := cardfile.Load(filePath)
+	if err != nil {
+		h.index.Update(filePath, mtime, nil)
+		return
+	}
+	h.index.Update(filePath, mtime, cardFields(parsed))
+}
+
+// indexNote 角色备注修改过时重新加入全文索引
+func (h *CardsHandler) indexNote(notePath string) {
+	stats, err := os.Stat(notePath)
+	if err != nil {
+		return
+	}
+	mtime := stats.ModTime().Format(time.RFC3339Nano)
+	if h.index.Has(notePath, mtime) {
+		return
+	}
+	content, err := os.ReadFile(notePath)
+	if err != nil {
+		slog.Warn("读取角色备注失败", "路径", notePath, "error", err)
+		return
+	}
+	h.index.Update(notePath, mtime, []fulltext.Field{{Name: "note", Text: string(content)}})
+}
+
+// cardFields 角色卡中参与全文搜索的字段，字段名与角色卡 JSON 一致
+func cardFields(c *card.Card) []fulltext.Field {
+	if c == nil {
+		return nil
+	}
+	data := c.Data
+	fields := []fulltext.Field{
+		{Name: "description", Text: data.Description},
+		{Name: "personality", Text: data.Personality},
+		{Name: "scenario", Text: data.Scenario},
+		{Name: "first_mes", Text: data.FirstMes},
+		{Name: "alternate_greetings", Text: strings.Join(data.AlternateGreetings, "\n\n")},
+	}
+	if data.CharacterBook != nil {
+		contents := make([]string, 0, len(data.CharacterBook.Entries))
+		for _, entry := range data.CharacterBook.Entries {
+			contents = append(contents, entry.Content)
+		}
+		fields = append(fields, fulltext.Field{Name: "lorebook", Text: strings.Join(contents, "\n\n")})
+	}
+
+	nonEmpty := fields[:0]
+	for _, field := range fields {
+		if strings.TrimSpace(field.Text) != "" {
+			nonEmpty = append(nonEmpty, field)
+		}
+	}
+	return nonEmpty
+}
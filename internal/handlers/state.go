@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/sidecar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// SetCharacterState 设置角色的收藏、评分和状态，保存在角色文件夹的附属数据中，不修改角色卡文件
+func (h *CardsHandler) SetCharacterState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.CharacterStateRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	folderPath, ok := h.characterFolder(w, req.FolderPath)
+	if !ok {
+		return
+	}
+	if req.Favorite == nil && req.Rating == nil && req.Status == nil {
+		writeErrorResponse(w, http.StatusBadRequest, "没有要修改的内容", nil)
+		return
+	}
+	if req.Rating != nil && (*req.Rating < 0 || *req.Rating > sidecar.MaxRating) {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("评分应为 1 到 %d，0 表示清除评分", sidecar.MaxRating), nil)
+		return
+	}
+	if req.Status != nil && statusRank(*req.Status) < 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "状态无效，可选值: "+strings.Join(sidecar.Statuses, "、"), nil)
+		return
+	}
+	defer h.cacheManager.Save()
+
+	step, err := updateSidecar(h.trash, folderPath, func(s *sidecar.Sidecar) error {
+		if req.Favorite != nil {
+			s.Favorite = *req.Favorite
+		}
+		if req.Rating != nil {
+			s.Rating = *req.Rating
+		}
+		if req.Status != nil {
+			s.Status = *req.Status
+			if s.Status == sidecar.StatusNew {
+				s.Status = ""
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "保存角色状态失败", err)
+		return
+	}
+	h.journal.Record(opCharacterState, "修改角色 "+filepath.Base(folderPath)+" 的状态", step)
+
+	character := h.processCharacterDirectory(folderPath)
+	if character != nil {
+		slog.Info("⭐ 角色状态已保存", "角色", filepath.Base(folderPath), "收藏", character.Favorite, "评分", character.Rating, "状态", character.Status)
+	}
+	writeSuccessResponse(w, "角色状态已保存", character)
+}
+
+// characterStatus 附属数据中记录的角色状态，未设置或无法识别时为 new
+func characterStatus(notes *sidecar.Sidecar) string {
+	if statusRank(notes.Status) < 0 {
+		return sidecar.StatusNew
+	}
+	return notes.Status
+}
+
+// statusRank 状态在 sidecar.Statuses 中的顺序，无效的状态返回 -1
+func statusRank(status string) int {
+	for i, item := range sidecar.Statuses {
+		if item == status {
+			return i
+		}
+	}
+	return -1
+}
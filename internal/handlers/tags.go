@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/journal"
+	"card-manager/internal/pkg/sidecar"
+	"card-manager/internal/pkg/tags"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ListTags 列出角色库中所有标签及使用它们的角色数，包括新建但还没有用到的标签
+func (h *CardsHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	defer h.cacheManager.Save()
+
+	result, err := h.tagCounts()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "获取标签失败", err)
+		return
+	}
+	writeSuccessResponse(w, fmt.Sprintf("共 %d 个标签", len(result)), result)
+}
+
+// CreateTag 新建标签，新建的标签即使没有角色使用也会列出
+func (h *CardsHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.TagRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	tag, reason := tags.Normalize(req.Name)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+
+	step, err := journaledWrite(h.trash, h.tags.Path(), func() error {
+		return h.tags.Update(func(registry *tags.Registry) error {
+			return registry.Create(tag)
+		})
+	})
+	if errors.Is(err, tags.ErrExists) {
+		writeErrorResponse(w, http.StatusConflict, "标签已存在: "+tag, nil)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "新建标签失败", err)
+		return
+	}
+	h.journal.Record(opCreateTag, "新建标签 "+tag, step)
+	slog.Info("🏷️ 标签已创建", "标签", tag)
+	writeSuccessResponse(w, "标签已创建: "+tag, map[string]string{"name": tag})
+}
+
+// RenameTag 在整个角色库中重命名标签，新名称已被使用时需要改用合并
+func (h *CardsHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.RenameTagRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	name, reason := tags.Normalize(req.Name)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	newName, reason := tags.Normalize(req.NewName)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	if newName == name {
+		writeErrorResponse(w, http.StatusBadRequest, "新名称与原名称相同", nil)
+		return
+	}
+	defer h.cacheManager.Save()
+
+	existing, ok := h.existingTags(w)
+	if !ok {
+		return
+	}
+	if !tags.Contains(existing, name) {
+		writeErrorResponse(w, http.StatusNotFound, "标签不存在: "+name, nil)
+		return
+	}
+	// 只改大小写时新名称就是它自己
+	if !strings.EqualFold(name, newName) && tags.Contains(existing, newName) {
+		writeErrorResponse(w, http.StatusConflict, fmt.Sprintf("标签 %s 已存在，请使用合并", newName), nil)
+		return
+	}
+
+	changed, steps, err := h.renameTag(name, newName)
+	h.journal.Record(opRenameTag, fmt.Sprintf("重命名标签 %s 为 %s", name, newName), steps...)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "重命名标签失败", err)
+		return
+	}
+	slog.Info("🏷️ 标签已重命名", "原标签", name, "新标签", newName, "角色", changed)
+	writeSuccessResponse(w, fmt.Sprintf("标签 %s 已重命名为 %s", name, newName), map[string]interface{}{
+		"name":              newName,
+		"changedCharacters": changed,
+	})
+}
+
+// MergeTags 把若干标签合并到目标标签，目标标签不存在时新建
+func (h *CardsHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.MergeTagsRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	target, reason := tags.Normalize(req.Target)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	sources := make([]string, 0, len(req.Sources))
+	for _, source := range req.Sources {
+		source, reason := tags.Normalize(source)
+		if reason != "" {
+			writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+			return
+		}
+		if source != target {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少要合并的标签", nil)
+		return
+	}
+	defer h.cacheManager.Save()
+
+	existing, ok := h.existingTags(w)
+	if !ok {
+		return
+	}
+	for _, source := range sources {
+		if !tags.Contains(existing, source) {
+			writeErrorResponse(w, http.StatusNotFound, "标签不存在: "+source, nil)
+			return
+		}
+	}
+
+	changed := 0
+	var steps []journal.Step
+	defer func() {
+		steps = collapseWrites(h.trash, steps)
+		h.journal.Record(opMergeTags, fmt.Sprintf("合并标签 %s 到 %s", strings.Join(sources, "、"), target), steps...)
+	}()
+	for _, source := range sources {
+		count, sourceSteps, err := h.renameTag(source, target)
+		changed += count
+		steps = append(steps, sourceSteps...)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "合并标签失败: "+source, err)
+			return
+		}
+	}
+	slog.Info("🏷️ 标签已合并", "来源", sources, "目标", target, "角色", changed)
+	writeSuccessResponse(w, fmt.Sprintf("%d 个标签已合并到 %s", len(sources), target), map[string]interface{}{
+		"name":              target,
+		"changedCharacters": changed,
+	})
+}
+
+// DeleteTag 从整个角色库中删除标签，角色卡内嵌的同名标签不再显示，角色卡文件不会修改
+func (h *CardsHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.TagRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	name, reason := tags.Normalize(req.Name)
+	if reason != "" {
+		writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+		return
+	}
+	defer h.cacheManager.Save()
+
+	existing, ok := h.existingTags(w)
+	if !ok {
+		return
+	}
+	if !tags.Contains(existing, name) {
+		writeErrorResponse(w, http.StatusNotFound, "标签不存在: "+name, nil)
+		return
+	}
+
+	changed, steps, err := h.renameTag(name, "")
+	h.journal.Record(opDeleteTag, "删除标签 "+name, steps...)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "删除标签失败", err)
+		return
+	}
+	slog.Info("🏷️ 标签已删除", "标签", name, "角色", changed)
+	writeSuccessResponse(w, "标签已删除: "+name, map[string]interface{}{"changedCharacters": changed})
+}
+
+// SetCharacterTags 设置角色的用户标签，保存在角色文件夹的附属数据中
+func (h *CardsHandler) SetCharacterTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.CharacterTagsRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	folderPath, ok := h.characterFolder(w, req.FolderPath)
+	if !ok {
+		return
+	}
+	userTags := make([]string, 0, len(req.Tags))
+	for _, tag := range req.Tags {
+		tag, reason := tags.Normalize(tag)
+		if reason != "" {
+			writeErrorResponse(w, http.StatusBadRequest, reason, nil)
+			return
+		}
+		userTags = append(userTags, tag)
+	}
+	defer h.cacheManager.Save()
+
+	step, err := updateSidecar(h.trash, folderPath, func(s *sidecar.Sidecar) error {
+		s.Tags = tags.Merge(userTags)
+		return nil
+	})
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "保存角色标签失败", err)
+		return
+	}
+	h.journal.Record(opCharacterTags, "修改角色 "+filepath.Base(folderPath)+" 的标签", step)
+	slog.Info("🏷️ 角色标签已保存", "角色", filepath.Base(folderPath), "标签", userTags)
+	writeSuccessResponse(w, "角色标签已保存", h.processCharacterDirectory(folderPath))
+}
+
+// withinRoot 判断 path 是否为 rootPath 本身或位于其中
+//
+// 按相对路径判断，不会把 <root>-other 这样名称以根目录开头的同级文件夹当作在根目录中。
+func withinRoot(rootPath, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(rootPath), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// characterFolder 检查请求中的角色文件夹路径，失败时直接写出错误响应
+func (h *CardsHandler) characterFolder(w http.ResponseWriter, folderPath string) (string, bool) {
+	if folderPath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少角色文件夹路径", nil)
+		return "", false
+	}
+	rootPath := filepath.Clean(h.config.CharactersRootPath)
+	folderPath = filepath.Clean(folderPath)
+	if !withinRoot(rootPath, folderPath) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return "", false
+	}
+	if info, err := os.Stat(folderPath); err != nil || !info.IsDir() {
+		writeErrorResponse(w, http.StatusNotFound, "角色文件夹不存在", err)
+		return "", false
+	}
+	if folderDepth(rootPath, folderPath) < 2 || classifyFolder(folderPath) != folderCharacter {
+		writeErrorResponse(w, http.StatusBadRequest, "不是角色文件夹", nil)
+		return "", false
+	}
+	return folderPath, true
+}
+
+// tagCounts 统计每个标签的角色数，按角色数从多到少排列
+func (h *CardsHandler) tagCounts() ([]models.TagInfo, error) {
+	_, characters, err := h.collectCharacters(nil)
+	if err != nil {
+		return nil, err
+	}
+	registry, err := h.tags.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]*models.TagInfo)
+	names := make([]string, 0)
+	info := func(tag string) *models.TagInfo {
+		key := strings.ToLower(tag)
+		if counts[key] == nil {
+			counts[key] = &models.TagInfo{Name: tag}
+			names = append(names, key)
+		}
+		return counts[key]
+	}
+	for _, tag := range registry.Tags {
+		info(tag)
+	}
+	for _, listed := range characters {
+		for _, tag := range listed.character.Tags {
+			item := info(tag)
+			item.Count++
+			if tags.Contains(listed.character.UserTags, tag) {
+				item.UserCount++
+			}
+		}
+	}
+
+	result := make([]models.TagInfo, 0, len(names))
+	for _, name := range names {
+		result = append(result, *counts[name])
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result, nil
+}
+
+// existingTags 列出角色库中所有的标签名，失败时直接写出错误响应
+func (h *CardsHandler) existingTags(w http.ResponseWriter) ([]string, bool) {
+	counts, err := h.tagCounts()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "获取标签失败", err)
+		return nil, false
+	}
+	names := make([]string, 0, len(counts))
+	for _, item := range counts {
+		names = append(names, item.Name)
+	}
+	return names, true
+}
+
+// renameTag 在登记表和所有角色的用户标签中把 old 改为 tag，tag 为空串时删除
+//
+// 返回修改了的角色数和已完成的操作步骤，出错时已完成的步骤同样返回以便记入操作日志。
+func (h *CardsHandler) renameTag(old, tag string) (int, []journal.Step, error) {
+	step, err := journaledWrite(h.trash, h.tags.Path(), func() error {
+		return h.tags.Update(func(registry *tags.Registry) error {
+			registry.Rename(old, tag)
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	steps := []journal.Step{step}
+
+	scan, err := scanLibrary(h.config.CharactersRootPath)
+	if err != nil {
+		return 0, steps, err
+	}
+	changed := 0
+	for _, folder := range scan.characters {
+		notes, err := sidecar.Load(folder.path)
+		if err != nil || !tags.Contains(notes.Tags, old) {
+			continue
+		}
+		step, err := updateSidecar(h.trash, folder.path, func(s *sidecar.Sidecar) error {
+			s.Tags, _ = tags.Replace(s.Tags, old, tag)
+			return nil
+		})
+		if err != nil {
+			return changed, steps, fmt.Errorf("修改角色 %s 的标签失败: %w", filepath.Base(folder.path), err)
+		}
+		steps = append(steps, step)
+		changed++
+	}
+	return changed, steps, nil
+}
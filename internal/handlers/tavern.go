@@ -4,13 +4,14 @@ import (
 	"card-manager/internal/config"
 	"card-manager/internal/models"
 	"card-manager/internal/pkg/cache"
+	"card-manager/internal/pkg/journal"
 	"card-manager/internal/pkg/localization"
+	"card-manager/internal/pkg/trash"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 // TavernHandler 处理Tavern集成相关的API请求
@@ -18,15 +19,19 @@ type TavernHandler struct {
 	config              *config.Config
 	cacheManager        *cache.Manager
 	localizationService *localization.Service
+	trash               *trash.Bin
+	journal             *journal.Journal
 }
 
 // 创建新的Tavern处理器
-func NewTavernHandler(config *config.Config, cacheManager *cache.Manager) *TavernHandler {
+func NewTavernHandler(config *config.Config, cacheManager *cache.Manager, trashBin *trash.Bin, operations *journal.Journal) *TavernHandler {
 	localizationService := localization.NewService(config.TavernPublicPath, config.Proxy)
 	return &TavernHandler{
 		config:              config,
 		cacheManager:        cacheManager,
 		localizationService: localizationService,
+		trash:               trashBin,
+		journal:             operations,
 	}
 }
 
@@ -47,7 +52,7 @@ func (h *TavernHandler) LocalizeCard(w http.ResponseWriter, r *http.Request) {
 	cleanCardPath := filepath.Clean(req.CardPath)
 	cleanRootPath := filepath.Clean(h.config.CharactersRootPath)
 	
-	if !strings.HasPrefix(cleanCardPath, cleanRootPath) {
+	if !withinRoot(cleanRootPath, cleanCardPath) {
 		slog.Error("路径非法", "cardPath", cleanCardPath, "rootPath", cleanRootPath)
 		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
 		return
@@ -159,7 +164,7 @@ func (h *TavernHandler) GetFaces(w http.ResponseWriter, r *http.Request) {
 	for _, file := range files {
 		if !file.IsDir() {
 			fileName := file.Name()
-			if h.isImageFile(fileName) {
+			if isImageFile(fileName) {
 				imageFiles = append(imageFiles, filepath.Join(faceDir, fileName))
 			}
 		}
@@ -211,10 +216,14 @@ func (h *TavernHandler) saveNote(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	notePath := filepath.Join(req.FolderPath, "note.md")
-	if err := os.WriteFile(notePath, []byte(req.Content), 0644); err != nil {
+	step, err := journaledWrite(h.trash, notePath, func() error {
+		return os.WriteFile(notePath, []byte(req.Content), 0644)
+	})
+	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "保存备注失败", err)
 		return
 	}
+	h.journal.Record(opSaveNote, "保存角色备注 "+filepath.Base(req.FolderPath), step)
 	
 	slog.Info("📝 备注已保存", "路径", notePath)
 	writeSuccessResponse(w, "备注已保存", nil)
@@ -229,13 +238,3 @@ func (h *TavernHandler) checkLocalizationNeeded(cardPath string) (bool, error) {
 func (h *TavernHandler) runLocalizationWithStreaming(cardPath string, sendMessage func(string, string)) (string, error) {
 	return h.localizationService.RunLocalizationWithStreaming(cardPath, sendMessage)
 }
-
-// isImageFile 检查文件是否为图片文件
-func (h *TavernHandler) isImageFile(fileName string) bool {
-	lowerName := strings.ToLower(fileName)
-	return strings.HasSuffix(lowerName, ".jpg") ||
-		strings.HasSuffix(lowerName, ".jpeg") ||
-		strings.HasSuffix(lowerName, ".png") ||
-		strings.HasSuffix(lowerName, ".gif") ||
-		strings.HasSuffix(lowerName, ".webp")
-}
\ No newline at end of file
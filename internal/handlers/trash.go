@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"card-manager/internal/config"
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/journal"
+	"card-manager/internal/pkg/trash"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// TrashHandler 处理回收站相关的API请求
+type TrashHandler struct {
+	config  *config.Config
+	trash   *trash.Bin
+	journal *journal.Journal
+}
+
+// NewTrashHandler 创建新的回收站处理器
+func NewTrashHandler(config *config.Config, trashBin *trash.Bin, operations *journal.Journal) *TrashHandler {
+	return &TrashHandler{
+		config:  config,
+		trash:   trashBin,
+		journal: operations,
+	}
+}
+
+// ListTrash 列出回收站中的条目，最近删除的在前
+func (h *TrashHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	items, err := h.trash.List()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "读取回收站失败", err)
+		return
+	}
+
+	response := models.TrashResponse{
+		Items:         make([]models.TrashItem, 0, len(items)),
+		RetentionDays: int(h.config.TrashRetention() / (24 * time.Hour)),
+	}
+	for _, item := range items {
+		response.Items = append(response.Items, models.TrashItem{
+			ID:           item.ID,
+			Kind:         item.Kind,
+			Name:         item.Name,
+			OriginalPath: item.OriginalPath,
+			DeletedAt:    item.DeletedAt.Format(time.RFC3339),
+			Size:         item.Size,
+		})
+	}
+	writeSuccessResponse(w, fmt.Sprintf("回收站中有 %d 个条目", len(response.Items)), response)
+}
+
+// RestoreTrash 将回收站条目恢复到原来的位置
+func (h *TrashHandler) RestoreTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.TrashRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+
+	restoredPath, err := h.trash.Restore(req.ID)
+	if err != nil {
+		if errors.Is(err, trash.ErrNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, err.Error(), err)
+			return
+		}
+		writeErrorResponse(w, http.StatusInternalServerError, "恢复失败", err)
+		return
+	}
+
+	// 撤销恢复即把文件重新移入回收站
+	h.journal.Record(opRestoreTrash, "从回收站恢复 "+filepath.Base(restoredPath), journal.Created(restoredPath))
+	slog.Info("♻️ 已从回收站恢复", "路径", restoredPath)
+	writeSuccessResponse(w, "已恢复到: "+restoredPath, map[string]string{
+		"fileName": filepath.Base(restoredPath),
+		"path":     restoredPath,
+	})
+}
+
+// PurgeTrash 永久删除回收站条目，all 为 true 时清空回收站
+func (h *TrashHandler) PurgeTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.TrashRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+
+	if req.All {
+		purged, err := h.trash.PurgeBefore(time.Now())
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "清空回收站失败", err)
+			return
+		}
+		slog.Info("🔥 回收站已清空", "数量", len(purged))
+		writeSuccessResponse(w, fmt.Sprintf("已永久删除 %d 个条目", len(purged)), nil)
+		return
+	}
+
+	if err := h.trash.Purge(req.ID); err != nil {
+		if errors.Is(err, trash.ErrNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, err.Error(), err)
+			return
+		}
+		writeErrorResponse(w, http.StatusInternalServerError, "永久删除失败", err)
+		return
+	}
+	slog.Info("🔥 回收站条目已永久删除", "ID", req.ID)
+	writeSuccessResponse(w, "已永久删除", nil)
+}
+
+// PurgeExpired 永久删除超过保留期限的回收站条目
+func (h *TrashHandler) PurgeExpired() {
+	retention := h.config.TrashRetention()
+	if retention == 0 {
+		return
+	}
+	purged, err := h.trash.PurgeBefore(time.Now().Add(-retention))
+	if err != nil {
+		slog.Warn("自动清理回收站失败", "error", err)
+	}
+	if len(purged) > 0 {
+		slog.Info("🔥 已自动清理过期的回收站条目", "数量", len(purged))
+	}
+}
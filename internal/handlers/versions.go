@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"card-manager/internal/models"
+	"card-manager/internal/pkg/cardfile"
+	"card-manager/internal/pkg/sidecar"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetVersionNote 设置版本的标签和备注，保存在角色文件夹的附属文件中
+func (h *CardsHandler) SetVersionNote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.VersionNoteRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	if req.VersionPath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少版本文件路径", nil)
+		return
+	}
+	if !withinRoot(h.config.CharactersRootPath, req.VersionPath) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+	if !cardfile.IsCardFile(req.VersionPath) {
+		writeErrorResponse(w, http.StatusBadRequest, "只支持 PNG 和 CHARX 文件", nil)
+		return
+	}
+	if _, err := os.Stat(req.VersionPath); err != nil {
+		writeErrorResponse(w, http.StatusNotFound, "版本文件不存在", err)
+		return
+	}
+
+	metadata, err := h.getCardMetadata(req.VersionPath)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "读取版本信息失败", err)
+		return
+	}
+	defer h.cacheManager.Save()
+
+	label := strings.TrimSpace(req.Label)
+	comment := strings.TrimSpace(req.Comment)
+	fileName := filepath.Base(req.VersionPath)
+	step, err := updateSidecar(h.trash, filepath.Dir(req.VersionPath), func(s *sidecar.Sidecar) error {
+		s.SetVersionNote(metadata.Hash, fileName, label, comment)
+		return nil
+	})
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "保存版本备注失败", err)
+		return
+	}
+	h.journal.Record(opVersionNote, "修改版本备注 "+fileName, step)
+
+	version := h.cardVersion(req.VersionPath)
+	version.Label = label
+	version.Comment = comment
+
+	message := "版本备注已保存"
+	if label == "" && comment == "" {
+		message = "版本备注已清除"
+	}
+	slog.Info("🏷️ "+message, "文件", fileName, "标签", label)
+	writeSuccessResponse(w, message, version)
+}
+
+// SetCurrentVersion 手动指定角色的当前版本，不再随文件修改时间变化
+//
+// 当前版本决定角色的最新版本路径、导入状态比较和本地化检查，
+// versionPath 为空时取消指定，恢复为修改时间最新的版本。
+func (h *CardsHandler) SetCurrentVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "方法不允许", nil)
+		return
+	}
+
+	var req models.CurrentVersionRequest
+	if err := decodeJSONRequest(r, &req); err != nil {
+		handleAppError(w, err.(*models.AppError))
+		return
+	}
+	if req.FolderPath == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "缺少角色文件夹路径", nil)
+		return
+	}
+	rootPath := filepath.Clean(h.config.CharactersRootPath)
+	if !withinRoot(rootPath, req.FolderPath) {
+		writeErrorResponse(w, http.StatusForbidden, "路径非法", nil)
+		return
+	}
+	if info, err := os.Stat(req.FolderPath); err != nil || !info.IsDir() {
+		writeErrorResponse(w, http.StatusNotFound, "角色文件夹不存在", err)
+		return
+	}
+
+	var current *sidecar.VersionKey
+	if req.VersionPath != "" {
+		if filepath.Dir(filepath.Clean(req.VersionPath)) != filepath.Clean(req.FolderPath) {
+			writeErrorResponse(w, http.StatusBadRequest, "版本文件不在该角色文件夹中", nil)
+			return
+		}
+		if !cardfile.IsCardFile(req.VersionPath) {
+			writeErrorResponse(w, http.StatusBadRequest, "只支持 PNG 和 CHARX 文件", nil)
+			return
+		}
+		metadata, err := h.getCardMetadata(req.VersionPath)
+		if err != nil {
+			writeErrorResponse(w, http.StatusNotFound, "版本文件不存在", err)
+			return
+		}
+		current = &sidecar.VersionKey{Hash: metadata.Hash, FileName: filepath.Base(req.VersionPath)}
+	}
+	defer h.cacheManager.Save()
+
+	step, err := updateSidecar(h.trash, req.FolderPath, func(s *sidecar.Sidecar) error {
+		s.CurrentVersion = current
+		return nil
+	})
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "保存当前版本失败", err)
+		return
+	}
+	h.journal.Record(opCurrentVersion, "指定角色 "+filepath.Base(req.FolderPath)+" 的当前版本", step)
+
+	character := h.processCharacterDirectory(req.FolderPath)
+	if current == nil {
+		slog.Info("📌 已取消指定当前版本", "角色", filepath.Base(req.FolderPath))
+		writeSuccessResponse(w, "已取消指定当前版本，改为使用最新修改的版本", character)
+		return
+	}
+	slog.Info("📌 已指定当前版本", "角色", filepath.Base(req.FolderPath), "文件", current.FileName)
+	writeSuccessResponse(w, "当前版本已设置为: "+current.FileName, character)
+}
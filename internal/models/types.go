@@ -1,11 +1,22 @@
 package models
 
+import "encoding/json"
+
 // CardVersion 代表一个卡片的特定版本
 type CardVersion struct {
 	Path         string `json:"path"`
 	FileName     string `json:"fileName"`
 	Mtime        string `json:"mtime"`
 	InternalName string `json:"internalName"`
+	// Problems 文件完整性问题，如 "truncated"、"CRC mismatch"、"no chara chunk"
+	Problems []string `json:"problems,omitempty"`
+	// LorebookEntries 内嵌世界书的条目数，没有世界书时为 0
+	LorebookEntries int `json:"lorebookEntries,omitempty"`
+	// Label 和 Comment 为用户给版本添加的标签和备注，保存在角色文件夹的附属文件中
+	Label   string `json:"label,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	// IsCurrent 是否为角色的当前版本
+	IsCurrent bool `json:"isCurrent,omitempty"`
 }
 
 // Character 代表一个角色
@@ -21,6 +32,17 @@ type Character struct {
 	HasFaceFolder      bool          `json:"hasFaceFolder"`
 	LocalizationNeeded *bool         `json:"localizationNeeded,omitempty"`
 	IsLocalized        bool          `json:"isLocalized"`
+	// CurrentPinned 为 true 时 LatestVersionPath 是手动指定的当前版本，而非修改时间最新的版本
+	CurrentPinned bool `json:"currentPinned"`
+	// Creator 当前版本的作者
+	Creator string `json:"creator,omitempty"`
+	// Tags 当前版本内嵌的标签与用户标签合并后的结果，UserTags 只含用户添加的标签
+	Tags     []string `json:"tags"`
+	UserTags []string `json:"userTags"`
+	// Favorite 是否收藏，Rating 为 1–5 的评分（0 表示未评分），Status 为 new、tried、keeper 或 dropped
+	Favorite bool   `json:"favorite"`
+	Rating   int    `json:"rating"`
+	Status   string `json:"status"`
 }
 
 // ImportInfo 包含卡片的导入状态
@@ -34,12 +56,244 @@ type ImportInfo struct {
 type StrayCard struct {
 	FileName string `json:"fileName"`
 	Path     string `json:"path"`
+	// Category 卡片所在分类的路径，子分类如 "动漫/原神"
+	Category string `json:"category"`
 }
 
 // CardsResponse 是 /api/cards 端点的响应结构
+//
+// Categories 以分类路径为键（子分类如 "动漫/原神"），只包含直接位于该分类中的角色。
 type CardsResponse struct {
 	Categories map[string][]Character `json:"categories"`
 	StrayCards []StrayCard            `json:"strayCards"`
+	// CategoryTree 分类的层级结构
+	CategoryTree []CategoryNode `json:"categoryTree"`
+}
+
+// CategoryNode 分类树中的一个分类，角色列表见 CardsResponse.Categories 中以 Path 为键的条目
+type CategoryNode struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	FolderPath string `json:"folderPath"`
+	// CharacterCount 直接位于该分类中的角色数，TotalCharacters 包括所有子分类中的角色
+	CharacterCount  int            `json:"characterCount"`
+	TotalCharacters int            `json:"totalCharacters"`
+	Children        []CategoryNode `json:"children"`
+}
+
+// BrokenFile 完整性报告中的一个损坏文件
+type BrokenFile struct {
+	Path      string   `json:"path"`
+	FileName  string   `json:"fileName"`
+	Category  string   `json:"category,omitempty"`
+	Character string   `json:"character,omitempty"`
+	Problems  []string `json:"problems"`
+}
+
+// IntegrityReport 是 /api/integrity-report 端点的响应结构
+type IntegrityReport struct {
+	CheckedFiles int          `json:"checkedFiles"`
+	BrokenFiles  []BrokenFile `json:"brokenFiles"`
+}
+
+// DuplicateItem 重复分组中的一个版本文件，待整理区的卡片没有分类和角色
+type DuplicateItem struct {
+	Path         string `json:"path"`
+	FileName     string `json:"fileName"`
+	Category     string `json:"category,omitempty"`
+	Character    string `json:"character,omitempty"`
+	FolderPath   string `json:"folderPath"`
+	InternalName string `json:"internalName"`
+	Hash         string `json:"hash"`
+	Mtime        string `json:"mtime"`
+}
+
+// DuplicateGroup 一组重复或疑似重复的版本
+//
+// Kind 为 identical（文件完全相同）、sameName（不同角色文件夹中的同名角色）
+// 或 similarDescription（描述几乎相同），Similarity 为组内最低的描述相似度。
+type DuplicateGroup struct {
+	Kind       string          `json:"kind"`
+	Key        string          `json:"key"`
+	Similarity float64         `json:"similarity,omitempty"`
+	Items      []DuplicateItem `json:"items"`
+}
+
+// DuplicatesReport 是 /api/duplicates 端点的响应结构
+type DuplicatesReport struct {
+	Groups []DuplicateGroup `json:"groups"`
+}
+
+// ResolveDuplicatesRequest 处理重复分组请求
+//
+// 保留 KeepPath，其余版本按 Action 处理：move 移入保留版本所在的角色文件夹，
+// delete 删除。
+type ResolveDuplicatesRequest struct {
+	KeepPath   string   `json:"keepPath"`
+	OtherPaths []string `json:"otherPaths"`
+	Action     string   `json:"action"`
+}
+
+// ImageMatch 与查询图片视觉相似的角色卡版本或卡面
+//
+// Kind 为 card（角色卡版本）或 face（卡面图片），Distance 为感知哈希相差的位数。
+type ImageMatch struct {
+	Path       string `json:"path"`
+	Kind       string `json:"kind"`
+	Category   string `json:"category,omitempty"`
+	Character  string `json:"character,omitempty"`
+	FolderPath string `json:"folderPath"`
+	Distance   int    `json:"distance"`
+}
+
+// CharacterImageMatch 角色卡或卡面与查询图片相似的角色，Distance 为最接近的一张
+type CharacterImageMatch struct {
+	Category   string       `json:"category"`
+	Character  string       `json:"character"`
+	FolderPath string       `json:"folderPath"`
+	Distance   int          `json:"distance"`
+	Matches    []ImageMatch `json:"matches"`
+}
+
+// SimilarImagesResponse 是相似图片查询的响应结构，结果按距离从近到远排列
+type SimilarImagesResponse struct {
+	ImageHash   string                `json:"imageHash"`
+	MaxDistance int                   `json:"maxDistance"`
+	Matches     []ImageMatch          `json:"matches"`
+	Characters  []CharacterImageMatch `json:"characters"`
+}
+
+// CharacterSummary 角色列表中的一个角色，不含版本列表
+type CharacterSummary struct {
+	Name               string     `json:"name"`
+	InternalName       string     `json:"internalName"`
+	FolderPath         string     `json:"folderPath"`
+	Category           string     `json:"category"`
+	LatestVersionPath  string     `json:"latestVersionPath"`
+	VersionCount       int        `json:"versionCount"`
+	ImportInfo         ImportInfo `json:"importInfo"`
+	HasNote            bool       `json:"hasNote"`
+	HasFaceFolder      bool       `json:"hasFaceFolder"`
+	LocalizationNeeded *bool      `json:"localizationNeeded,omitempty"`
+	IsLocalized        bool       `json:"isLocalized"`
+	CurrentPinned      bool       `json:"currentPinned"`
+	Creator            string     `json:"creator,omitempty"`
+	Tags               []string   `json:"tags"`
+	UserTags           []string   `json:"userTags"`
+	Favorite           bool       `json:"favorite"`
+	Rating             int        `json:"rating"`
+	Status             string     `json:"status"`
+	// LatestMtime 所有版本中最晚的修改时间
+	LatestMtime string `json:"latestMtime"`
+}
+
+// CharacterListResponse 是 /api/characters 端点的响应结构
+//
+// Total 为筛选后的角色总数；NextOffset 为下一页的 offset，没有下一页时省略。
+type CharacterListResponse struct {
+	Total      int                `json:"total"`
+	Offset     int                `json:"offset"`
+	Limit      int                `json:"limit"`
+	NextOffset *int               `json:"nextOffset,omitempty"`
+	Characters []CharacterSummary `json:"characters"`
+}
+
+// CharacterStateRequest 设置角色收藏、评分和状态的请求，省略的字段保持不变，Rating 为 0 表示清除评分
+type CharacterStateRequest struct {
+	FolderPath string  `json:"folderPath"`
+	Favorite   *bool   `json:"favorite"`
+	Rating     *int    `json:"rating"`
+	Status     *string `json:"status"`
+}
+
+// TagInfo 角色库中的一个标签及使用它的角色数
+type TagInfo struct {
+	Name string `json:"name"`
+	// Count 带有该标签的角色数，UserCount 为其中由用户添加该标签的角色数
+	Count     int `json:"count"`
+	UserCount int `json:"userCount"`
+}
+
+// TagRequest 新建或删除标签的请求
+type TagRequest struct {
+	Name string `json:"name"`
+}
+
+// RenameTagRequest 重命名标签的请求
+type RenameTagRequest struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName"`
+}
+
+// MergeTagsRequest 把若干标签合并为一个标签的请求
+type MergeTagsRequest struct {
+	Sources []string `json:"sources"`
+	Target  string   `json:"target"`
+}
+
+// CharacterTagsRequest 设置角色用户标签的请求，Tags 为完整的用户标签列表
+type CharacterTagsRequest struct {
+	FolderPath string   `json:"folderPath"`
+	Tags       []string `json:"tags"`
+}
+
+// CollectionRequest 保存智能收藏夹的请求，Query 与 /api/cards 的筛选参数相同
+type CollectionRequest struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// RenameCollectionRequest 重命名智能收藏夹的请求
+type RenameCollectionRequest struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName"`
+}
+
+// ImportCollectionsRequest 导入智能收藏夹的请求，格式与导出的文件相同
+type ImportCollectionsRequest struct {
+	Collections []CollectionRequest `json:"collections"`
+	// Replace 清空现有的收藏夹，否则同名的收藏夹被覆盖、其余保留
+	Replace bool `json:"replace,omitempty"`
+}
+
+// CollectionInfo 一个智能收藏夹及当前符合条件的角色数
+type CollectionInfo struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	Count int    `json:"count"`
+	// Error 筛选条件无效时的原因，此时 Count 为 0
+	Error string `json:"error,omitempty"`
+}
+
+// CollectionListResponse 是 /api/collections 端点的响应结构，分类树和智能收藏夹一起列出
+type CollectionListResponse struct {
+	Categories  []CategoryNode   `json:"categories"`
+	Collections []CollectionInfo `json:"collections"`
+}
+
+// SearchSnippet 搜索结果中命中文字所在的一段摘要
+type SearchSnippet struct {
+	// FileName 摘要所在的版本文件或备注文件
+	FileName string `json:"fileName"`
+	// Field 摘要所在的字段，如 description、first_mes、lorebook、note
+	Field string `json:"field"`
+	// HTML 已转义的摘要文本，命中的文字包在 <mark> 中
+	HTML string `json:"html"`
+}
+
+// SearchResult 全文搜索命中的一个角色
+type SearchResult struct {
+	Character Character       `json:"character"`
+	Category  string          `json:"category"`
+	Score     float64         `json:"score"`
+	Snippets  []SearchSnippet `json:"snippets"`
+}
+
+// SearchResponse 是 /api/search 端点的响应结构，Total 为截取前命中的角色数
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Total   int            `json:"total"`
+	Results []SearchResult `json:"results"`
 }
 
 // StatsResponse 是 /api/stats 端点的响应结构
@@ -78,12 +332,89 @@ type DeleteVersionRequest struct {
 	FilePath string `json:"filePath"`
 }
 
+// DeleteCharacterRequest 删除角色请求，整个角色文件夹移入回收站
+type DeleteCharacterRequest struct {
+	FolderPath string `json:"folderPath"`
+}
+
+// CategoryRequest 新建或删除分类请求，Path 为以 / 分隔的分类路径
+type CategoryRequest struct {
+	Path string `json:"path"`
+}
+
+// RenameCategoryRequest 重命名或移动分类请求
+type RenameCategoryRequest struct {
+	Path    string `json:"path"`
+	NewPath string `json:"newPath"`
+}
+
+// MergeCategoriesRequest 将 Source 分类中的所有内容移入 Target 分类
+type MergeCategoriesRequest struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// TrashItem 回收站中的一个条目
+//
+// Kind 为 version（角色版本）、stray（待整理卡片）或 character（整个角色文件夹）。
+type TrashItem struct {
+	ID           string `json:"id"`
+	Kind         string `json:"kind"`
+	Name         string `json:"name"`
+	OriginalPath string `json:"originalPath"`
+	DeletedAt    string `json:"deletedAt"`
+	Size         int64  `json:"size"`
+}
+
+// TrashResponse 是 /api/trash 端点的响应结构，RetentionDays 为 0 表示不自动清理
+type TrashResponse struct {
+	Items         []TrashItem `json:"items"`
+	RetentionDays int         `json:"retentionDays"`
+}
+
+// TrashRequest 恢复或永久删除回收站条目请求，All 为 true 时清空回收站
+type TrashRequest struct {
+	ID  string `json:"id"`
+	All bool   `json:"all,omitempty"`
+}
+
+// JournalStep 操作中的一个文件变更
+type JournalStep struct {
+	Kind string `json:"kind"`
+	Path string `json:"path,omitempty"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// JournalOperation 操作日志中的一次操作
+type JournalOperation struct {
+	ID          string        `json:"id"`
+	Type        string        `json:"type"`
+	Description string        `json:"description"`
+	Time        string        `json:"time"`
+	Steps       []JournalStep `json:"steps"`
+	Undone      bool          `json:"undone"`
+}
+
+// UndoRequest 撤销请求，指定 ID 时撤销该操作，否则从最新的操作开始撤销 Count 个（默认 1 个）
+type UndoRequest struct {
+	ID    string `json:"id,omitempty"`
+	Count int    `json:"count,omitempty"`
+}
+
 // MoveCharacterRequest 移动角色请求
 type MoveCharacterRequest struct {
 	OldFolderPath string `json:"oldFolderPath"`
 	NewCategory   string `json:"newCategory"`
 }
 
+// RenameCharacterRequest 重命名角色文件夹请求，RenameInternal 为 true 时同时修改角色卡内的名称并保存为新版本
+type RenameCharacterRequest struct {
+	FolderPath     string `json:"folderPath"`
+	NewName        string `json:"newName"`
+	RenameInternal bool   `json:"renameInternal,omitempty"`
+}
+
 // OrganizeStrayRequest 整理待整理卡片请求
 type OrganizeStrayRequest struct {
 	StrayPath     string `json:"strayPath"`
@@ -112,4 +443,157 @@ type MergeJsonToPngRequest struct {
 	FolderPath   string `json:"folderPath"`
 	JsonFileName string `json:"jsonFileName"`
 	PngFileName  string `json:"pngFileName"`
-}
\ No newline at end of file
+	// OutputFileName 输出文件名，留空时为 <PNG名>_merged.png；Overwrite 为真且未指定时覆盖原 PNG
+	OutputFileName string `json:"outputFileName,omitempty"`
+	// Overwrite 允许覆盖已存在的输出文件，否则自动追加序号避免冲突
+	Overwrite bool `json:"overwrite,omitempty"`
+}
+
+// ConvertCardRequest PNG 与 CHARX 互相转换请求
+type ConvertCardRequest struct {
+	Path string `json:"path"`
+}
+
+// ReplaceAvatarRequest 替换角色卡头像请求
+//
+// 以 JSON 提交时使用 FacePath 指定卡面图片；以 multipart 表单提交时
+// 上传的图片放在 file 字段，其余字段同名。
+type ReplaceAvatarRequest struct {
+	VersionPath string `json:"versionPath"`
+	FacePath    string `json:"facePath,omitempty"`
+	// OutputFileName 输出文件名，留空时为 <版本名>_<图片名>.png
+	OutputFileName string `json:"outputFileName,omitempty"`
+}
+
+// LorebookEntry 内嵌世界书条目摘要
+type LorebookEntry struct {
+	Index          int      `json:"index"`
+	Name           string   `json:"name,omitempty"`
+	Comment        string   `json:"comment"`
+	Keys           []string `json:"keys"`
+	SecondaryKeys  []string `json:"secondaryKeys,omitempty"`
+	Enabled        bool     `json:"enabled"`
+	Constant       bool     `json:"constant"`
+	Position       string   `json:"position,omitempty"`
+	InsertionOrder int      `json:"insertionOrder"`
+}
+
+// LorebookResponse 是 /api/lorebook 端点的响应结构
+type LorebookResponse struct {
+	HasLorebook bool            `json:"hasLorebook"`
+	Name        string          `json:"name,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Entries     []LorebookEntry `json:"entries"`
+}
+
+// ExportLorebookRequest 导出世界书请求
+type ExportLorebookRequest struct {
+	Path string `json:"path"`
+	// Overwrite 覆盖已存在的同名文件，否则自动追加序号避免冲突
+	Overwrite bool `json:"overwrite,omitempty"`
+}
+
+// AttachLorebookRequest 将世界书写入角色卡请求
+//
+// 以 JSON 提交时使用 WorldPath 指定世界书文件；以 multipart 表单提交时
+// 上传的文件放在 file 字段，其余字段同名。
+type AttachLorebookRequest struct {
+	VersionPath string `json:"versionPath"`
+	WorldPath   string `json:"worldPath,omitempty"`
+	// OutputFileName 输出文件名，留空时为 <版本名>_lorebook
+	OutputFileName string `json:"outputFileName,omitempty"`
+}
+
+// CardFieldUpdates 角色卡字段的部分更新，未提供的字段保持不变
+type CardFieldUpdates struct {
+	Name               *string   `json:"name,omitempty"`
+	Description        *string   `json:"description,omitempty"`
+	Personality        *string   `json:"personality,omitempty"`
+	Scenario           *string   `json:"scenario,omitempty"`
+	FirstMes           *string   `json:"first_mes,omitempty"`
+	AlternateGreetings *[]string `json:"alternate_greetings,omitempty"`
+	SystemPrompt       *string   `json:"system_prompt,omitempty"`
+	Tags               *[]string `json:"tags,omitempty"`
+	CreatorNotes       *string   `json:"creator_notes,omitempty"`
+}
+
+// EditCardRequest 编辑角色卡字段请求
+type EditCardRequest struct {
+	VersionPath string          `json:"versionPath"`
+	Fields      json.RawMessage `json:"fields"`
+	// OutputFileName 输出文件名，留空时为 <版本名>_edited
+	OutputFileName string `json:"outputFileName,omitempty"`
+}
+
+// EditCardResponse 编辑角色卡字段的响应，包含新生成的版本
+type EditCardResponse struct {
+	Version       CardVersion `json:"version"`
+	UpdatedFields []string    `json:"updatedFields"`
+}
+
+// DiffLine 文本差异中的一行，Op 为 equal、insert 或 delete
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// FieldDiff 单个字段的差异，短字段给出新旧值，长文本字段给出逐行差异
+type FieldDiff struct {
+	Field string     `json:"field"`
+	Old   *string    `json:"old,omitempty"`
+	New   *string    `json:"new,omitempty"`
+	Lines []DiffLine `json:"lines,omitempty"`
+}
+
+// ListDiff 字符串列表的增删
+type ListDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// LorebookEntryChange 两个版本中都存在但内容有变化的世界书条目
+type LorebookEntryChange struct {
+	Entry        LorebookEntry `json:"entry"`
+	Changes      []string      `json:"changes"`
+	ContentLines []DiffLine    `json:"contentLines,omitempty"`
+}
+
+// LorebookDiff 内嵌世界书条目的增删改
+type LorebookDiff struct {
+	Added    []LorebookEntry       `json:"added"`
+	Removed  []LorebookEntry       `json:"removed"`
+	Modified []LorebookEntryChange `json:"modified"`
+}
+
+// VersionDiffResponse 是 /api/diff-versions 端点的响应结构
+type VersionDiffResponse struct {
+	OldPath            string       `json:"oldPath"`
+	NewPath            string       `json:"newPath"`
+	Identical          bool         `json:"identical"`
+	Fields             []FieldDiff  `json:"fields"`
+	AlternateGreetings ListDiff     `json:"alternateGreetings"`
+	Tags               ListDiff     `json:"tags"`
+	Lorebook           LorebookDiff `json:"lorebook"`
+	ExtensionsChanged  bool         `json:"extensionsChanged"`
+	AvatarChanged      bool         `json:"avatarChanged"`
+}
+
+// VersionNoteRequest 设置版本标签和备注请求，两者都为空时清除
+type VersionNoteRequest struct {
+	VersionPath string `json:"versionPath"`
+	Label       string `json:"label"`
+	Comment     string `json:"comment"`
+}
+
+// CurrentVersionRequest 指定角色当前版本请求，VersionPath 为空时取消指定
+type CurrentVersionRequest struct {
+	FolderPath  string `json:"folderPath"`
+	VersionPath string `json:"versionPath"`
+}
+
+// ExtractCardJsonRequest 从角色卡版本中导出 JSON 请求
+type ExtractCardJsonRequest struct {
+	Path string `json:"path"`
+	// Overwrite 覆盖已存在的同名 JSON 文件，否则自动追加序号避免冲突
+	Overwrite bool `json:"overwrite,omitempty"`
+}
@@ -3,6 +3,8 @@ package cache
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -12,13 +14,26 @@ type Entry struct {
 	InternalName       string `json:"internalName"`
 	Mtime              string `json:"mtime"`
 	LocalizationNeeded *bool  `json:"localizationNeeded,omitempty"`
+	// Problems 完整性检查发现的问题
+	Problems []string `json:"problems,omitempty"`
+	// LorebookEntries 内嵌世界书的条目数
+	LorebookEntries int `json:"lorebookEntries,omitempty"`
+	// ImageHash 头像或卡面的感知哈希（dHash），扫描时计算，无法解码时为空
+	ImageHash string `json:"imageHash,omitempty"`
+	// Creator 角色卡作者
+	Creator string `json:"creator,omitempty"`
+	// Tags 角色卡内嵌的标签
+	Tags []string `json:"tags,omitempty"`
+	// Schema 条目的元数据版本，低于当前版本的旧条目需要重新读取
+	Schema int `json:"schema,omitempty"`
 }
 
 // Manager 缓存管理器
 type Manager struct {
-	cache     map[string]Entry
-	mutex     sync.RWMutex
-	cachePath string
+	cache      map[string]Entry
+	mutex      sync.RWMutex
+	cachePath  string
+	generation int
 }
 
 // NewManager 创建新的缓存管理器
@@ -75,14 +90,48 @@ func (m *Manager) Set(key string, entry Entry) {
 	m.cache[key] = entry
 }
 
+// Rekey 将 oldPath 及其下所有文件的缓存条目改为以 newPath 为前缀，返回改动的条目数
+//
+// 条目以绝对路径为键，文件夹改名或移动后不迁移就会全部失效。
+func (m *Manager) Rekey(oldPath, newPath string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	oldPath, newPath = filepath.Clean(oldPath), filepath.Clean(newPath)
+	prefix := oldPath + string(filepath.Separator)
+	moved := make(map[string]Entry)
+	for key, entry := range m.cache {
+		if key == oldPath {
+			moved[newPath] = entry
+		} else if strings.HasPrefix(key, prefix) {
+			moved[newPath+key[len(oldPath):]] = entry
+		} else {
+			continue
+		}
+		delete(m.cache, key)
+	}
+	for key, entry := range moved {
+		m.cache[key] = entry
+	}
+	return len(moved)
+}
+
 // Clear 清除缓存
 func (m *Manager) Clear() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	m.cache = make(map[string]Entry)
+	m.generation++
 	return os.Remove(m.cachePath)
 }
 
+// Generation 缓存被清除的次数，依据缓存生成的数据可据此判断是否需要重新生成
+func (m *Manager) Generation() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.generation
+}
+
 // IsEmpty 检查缓存是否为空
 func (m *Manager) IsEmpty() bool {
 	m.mutex.RLock()
@@ -0,0 +1,94 @@
+package card
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// CharacterBook 角色卡内嵌的世界书（data.character_book）
+type CharacterBook struct {
+	Name              string      `json:"name,omitempty"`
+	Description       string      `json:"description,omitempty"`
+	ScanDepth         *int        `json:"scan_depth,omitempty"`
+	TokenBudget       *int        `json:"token_budget,omitempty"`
+	RecursiveScanning *bool       `json:"recursive_scanning,omitempty"`
+	Extensions        Extensions  `json:"extensions"`
+	Entries           []BookEntry `json:"entries"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// BookEntry 世界书条目
+type BookEntry struct {
+	Keys           []string   `json:"keys"`
+	Content        string     `json:"content"`
+	Extensions     Extensions `json:"extensions"`
+	Enabled        bool       `json:"enabled"`
+	InsertionOrder int        `json:"insertion_order"`
+	CaseSensitive  *bool      `json:"case_sensitive,omitempty"`
+	UseRegex       *bool      `json:"use_regex,omitempty"`
+	Name           string     `json:"name,omitempty"`
+	Priority       *int       `json:"priority,omitempty"`
+	// ID 在不同工具中可能是数字或字符串，保留原始值
+	ID            json.RawMessage `json:"id,omitempty"`
+	Comment       string          `json:"comment,omitempty"`
+	Selective     *bool           `json:"selective,omitempty"`
+	SecondaryKeys []string        `json:"secondary_keys,omitempty"`
+	Constant      *bool           `json:"constant,omitempty"`
+	Position      string          `json:"position,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// ParseCharacterBook 解析单独的世界书 JSON
+func ParseCharacterBook(raw []byte) (*CharacterBook, error) {
+	type plain CharacterBook
+	var book CharacterBook
+	if err := json.Unmarshal(raw, (*plain)(&book)); err != nil {
+		return nil, toFieldError(err)
+	}
+	book.collectExtra(raw)
+	return &book, nil
+}
+
+// MarshalJSON 序列化世界书并写回未识别的字段
+func (b CharacterBook) MarshalJSON() ([]byte, error) {
+	type plain CharacterBook
+	if b.Extensions == nil {
+		b.Extensions = Extensions{}
+	}
+	if b.Entries == nil {
+		b.Entries = []BookEntry{}
+	}
+	return marshalWithExtra(plain(b), b.Extra)
+}
+
+// MarshalJSON 序列化世界书条目并写回未识别的字段
+func (e BookEntry) MarshalJSON() ([]byte, error) {
+	type plain BookEntry
+	if e.Keys == nil {
+		e.Keys = []string{}
+	}
+	if e.Extensions == nil {
+		e.Extensions = Extensions{}
+	}
+	return marshalWithExtra(plain(e), e.Extra)
+}
+
+// collectExtra 从原始 JSON 中补齐世界书及其条目的未识别字段
+func (b *CharacterBook) collectExtra(raw json.RawMessage) {
+	b.Extra = extraFields(raw, reflect.TypeOf(CharacterBook{}))
+
+	var nested struct {
+		Entries []json.RawMessage `json:"entries"`
+	}
+	if json.Unmarshal(raw, &nested) != nil {
+		return
+	}
+	entryType := reflect.TypeOf(BookEntry{})
+	for i := range b.Entries {
+		if i < len(nested.Entries) {
+			b.Entries[i].Extra = extraFields(nested.Entries[i], entryType)
+		}
+	}
+}
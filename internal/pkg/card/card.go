@@ -0,0 +1,397 @@
+package card
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Version 角色卡规范版本
+type Version int
+
+const (
+	VersionUnknown Version = iota
+	V1
+	V2
+	V3
+)
+
+// 规范标识，对应卡片 JSON 中的 spec 字段
+const (
+	SpecV2 = "chara_card_v2"
+	SpecV3 = "chara_card_v3"
+
+	SpecVersionV2 = "2.0"
+	SpecVersionV3 = "3.0"
+)
+
+// String 返回版本的可读名称
+func (v Version) String() string {
+	switch v {
+	case V1:
+		return "V1"
+	case V2:
+		return "V2"
+	case V3:
+		return "V3"
+	default:
+		return "unknown"
+	}
+}
+
+// v1Keys V1 卡片中位于顶层的字段
+var v1Keys = []string{"name", "description", "personality", "scenario", "first_mes", "mes_example"}
+
+// Card 角色卡，兼容 V1、V2（chara_card_v2）和 V3（chara_card_v3）规范
+//
+// V1 卡片没有 data 包装，字段直接位于顶层；解析时会统一放入 Data，
+// 写回时再按原规范展开。未识别的顶层字段保存在 Extra 中并原样写回。
+type Card struct {
+	Spec        string
+	SpecVersion string
+	Data        Data
+	Extra       map[string]json.RawMessage
+
+	version Version
+}
+
+// Data 角色卡的主体数据（V2/V3 的 data 字段）
+type Data struct {
+	Name                    string         `json:"name"`
+	Description             string         `json:"description"`
+	Personality             string         `json:"personality"`
+	Scenario                string         `json:"scenario"`
+	FirstMes                string         `json:"first_mes"`
+	MesExample              string         `json:"mes_example"`
+	CreatorNotes            string         `json:"creator_notes"`
+	SystemPrompt            string         `json:"system_prompt"`
+	PostHistoryInstructions string         `json:"post_history_instructions"`
+	AlternateGreetings      []string       `json:"alternate_greetings"`
+	CharacterBook           *CharacterBook `json:"character_book,omitempty"`
+	Tags                    []string       `json:"tags"`
+	Creator                 string         `json:"creator"`
+	CharacterVersion        string         `json:"character_version"`
+	Extensions              Extensions     `json:"extensions"`
+
+	// 以下为 V3 新增字段
+	Assets                   []Asset           `json:"assets,omitempty"`
+	Nickname                 string            `json:"nickname,omitempty"`
+	CreatorNotesMultilingual map[string]string `json:"creator_notes_multilingual,omitempty"`
+	Source                   []string          `json:"source,omitempty"`
+	GroupOnlyGreetings       []string          `json:"group_only_greetings,omitempty"`
+	CreationDate             *int64            `json:"creation_date,omitempty"`
+	ModificationDate         *int64            `json:"modification_date,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Asset V3 卡片中的资源描述
+type Asset struct {
+	Type string `json:"type"`
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+	Ext  string `json:"ext"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Extensions 扩展字段，保留原始 JSON 以免数值等内容在往返中失真
+type Extensions map[string]json.RawMessage
+
+// Parse 解析角色卡 JSON，自动识别 V1/V2/V3 规范
+//
+// 社区卡片常有字段类型与规范不符（如数字形式的 character_version），
+// 严格解析失败时会先把这些字段转换为规范类型再解析，以免整张卡片不可读。
+// 需要报告字段错误时使用 ParseStrict。
+func Parse(raw []byte) (*Card, error) {
+	c, err := ParseStrict(raw)
+	var fieldErr *FieldError
+	if err == nil || !errors.As(err, &fieldErr) {
+		return c, err
+	}
+	if normalized, ok := normalizeCard(raw); ok {
+		if lenient, lenientErr := ParseStrict(normalized); lenientErr == nil {
+			return lenient, nil
+		}
+	}
+	return nil, err
+}
+
+// ParseStrict 按规范类型严格解析角色卡 JSON，字段类型不符时返回 *FieldError
+func ParseStrict(raw []byte) (*Card, error) {
+	var c Card
+	if err := json.Unmarshal(raw, &c); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Decode 解码 PNG 文本块中的 Base64 数据并解析为角色卡
+func Decode(encoded string) (*Card, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBase64, err)
+	}
+	return Parse(raw)
+}
+
+// Encode 将角色卡序列化为 Base64，用于写入 PNG 文本块
+func (c *Card) Encode() (string, error) {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// Version 返回卡片的规范版本
+func (c *Card) Version() Version {
+	switch c.Spec {
+	case SpecV3:
+		return V3
+	case SpecV2:
+		return V2
+	}
+	if c.version != VersionUnknown {
+		return c.version
+	}
+	return V1
+}
+
+// Name 返回角色名称，兼容旧格式的顶层 name/char_name 字段
+func (c *Card) Name() string {
+	if name := strings.TrimSpace(c.Data.Name); name != "" {
+		return name
+	}
+	for _, key := range []string{"name", "char_name"} {
+		var name string
+		if raw, ok := c.Extra[key]; ok && json.Unmarshal(raw, &name) == nil && strings.TrimSpace(name) != "" {
+			return strings.TrimSpace(name)
+		}
+	}
+	return ""
+}
+
+// UnmarshalJSON 识别规范并解析卡片
+func (c *Card) UnmarshalJSON(raw []byte) error {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &top); err != nil || top == nil {
+		return &FieldError{Message: "角色卡必须是 JSON 对象"}
+	}
+
+	var spec string
+	if rawSpec, ok := top["spec"]; ok {
+		if err := json.Unmarshal(rawSpec, &spec); err != nil {
+			return &FieldError{Field: "spec", Message: "应为字符串"}
+		}
+	}
+	rawData, hasData := top["data"]
+
+	*c = Card{Spec: spec}
+	switch {
+	case spec == SpecV2 || spec == SpecV3 || (spec == "" && hasData):
+		if !hasData || isNull(rawData) {
+			return &FieldError{Field: "data", Message: "缺少 data 字段"}
+		}
+		if rawVersion, ok := top["spec_version"]; ok {
+			if err := json.Unmarshal(rawVersion, &c.SpecVersion); err != nil {
+				return &FieldError{Field: "spec_version", Message: "应为字符串"}
+			}
+		}
+		if err := json.Unmarshal(rawData, &c.Data); err != nil {
+			return withFieldPrefix("data", err)
+		}
+		c.version = V2
+		if spec == SpecV3 {
+			c.version = V3
+		}
+		c.Extra = remainingFields(top, "spec", "spec_version", "data")
+	case spec == "":
+		if !looksLikeV1(top) {
+			return ErrNotCard
+		}
+		keys := v1DataKeys(top)
+		if err := unmarshalFields(top, keys, &c.Data); err != nil {
+			return err
+		}
+		c.version = V1
+		c.Extra = remainingFields(top, keys...)
+	default:
+		return &FieldError{Field: "spec", Message: fmt.Sprintf("不支持的角色卡规范 %q", spec)}
+	}
+	return nil
+}
+
+// MarshalJSON 按卡片原有规范序列化
+func (c Card) MarshalJSON() ([]byte, error) {
+	out := make(map[string]json.RawMessage, len(c.Extra)+3)
+	for k, v := range c.Extra {
+		out[k] = v
+	}
+
+	if c.Version() == V1 {
+		fields, err := c.Data.fields()
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fields {
+			// V1 规范之外的字段（如编辑后的 tags、character_book）非空时同样写在顶层
+			if slices.Contains(v1Keys, key) || !isEmptyValue(value) {
+				out[key] = value
+			}
+		}
+		return json.Marshal(out)
+	}
+
+	fields, err := c.Data.fields()
+	if err != nil {
+		return nil, err
+	}
+	if c.Version() == V3 {
+		if _, ok := fields["group_only_greetings"]; !ok {
+			fields["group_only_greetings"] = json.RawMessage("[]")
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	out["data"] = data
+	if c.Spec != "" {
+		out["spec"], _ = json.Marshal(c.Spec)
+	}
+	if c.SpecVersion != "" {
+		out["spec_version"], _ = json.Marshal(c.SpecVersion)
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 解析 data 字段并保留未识别的字段
+//
+// 嵌套的世界书和资源不实现 UnmarshalJSON，以便类型错误带上完整的字段路径，
+// 它们的未识别字段在这里统一补齐。
+func (d *Data) UnmarshalJSON(raw []byte) error {
+	type plain Data
+	if err := unmarshalWithExtra(raw, (*plain)(d), &d.Extra); err != nil {
+		return err
+	}
+
+	var nested struct {
+		CharacterBook json.RawMessage   `json:"character_book"`
+		Assets        []json.RawMessage `json:"assets"`
+	}
+	if err := json.Unmarshal(raw, &nested); err != nil {
+		return toFieldError(err)
+	}
+	if d.CharacterBook != nil {
+		d.CharacterBook.collectExtra(nested.CharacterBook)
+	}
+	for i := range d.Assets {
+		if i < len(nested.Assets) {
+			d.Assets[i].Extra = extraFields(nested.Assets[i], reflect.TypeOf(Asset{}))
+		}
+	}
+	return nil
+}
+
+// MarshalJSON 序列化 data 字段并写回未识别的字段
+func (d Data) MarshalJSON() ([]byte, error) {
+	fields, err := d.fields()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// fields 将 data 展开为字段表，缺省的数组和对象按规范补为空值
+func (d Data) fields() (map[string]json.RawMessage, error) {
+	type plain Data
+	if d.AlternateGreetings == nil {
+		d.AlternateGreetings = []string{}
+	}
+	if d.Tags == nil {
+		d.Tags = []string{}
+	}
+	if d.Extensions == nil {
+		d.Extensions = Extensions{}
+	}
+	return fieldsWithExtra(plain(d), d.Extra)
+}
+
+// MarshalJSON 序列化资源描述并写回未识别的字段
+func (a Asset) MarshalJSON() ([]byte, error) {
+	type plain Asset
+	return marshalWithExtra(plain(a), a.Extra)
+}
+
+// ToV2 返回降级为 V2 规范的副本，去掉 V3 专有字段
+func (c *Card) ToV2() *Card {
+	v2 := *c
+	v2.Spec = SpecV2
+	v2.SpecVersion = SpecVersionV2
+	v2.version = V2
+	v2.Data.Name = c.Name()
+	v2.Data.Assets = nil
+	v2.Data.Nickname = ""
+	v2.Data.CreatorNotesMultilingual = nil
+	v2.Data.Source = nil
+	v2.Data.GroupOnlyGreetings = nil
+	v2.Data.CreationDate = nil
+	v2.Data.ModificationDate = nil
+	return &v2
+}
+
+// ToV3 返回升级为 V3 规范的副本
+func (c *Card) ToV3() *Card {
+	v3 := *c
+	v3.Spec = SpecV3
+	v3.SpecVersion = SpecVersionV3
+	v3.version = V3
+	v3.Data.Name = c.Name()
+	if v3.Data.GroupOnlyGreetings == nil {
+		v3.Data.GroupOnlyGreetings = []string{}
+	}
+	return &v3
+}
+
+// looksLikeV1 判断没有 spec 的 JSON 对象是否为 V1 角色卡
+func looksLikeV1(top map[string]json.RawMessage) bool {
+	for _, key := range []string{"name", "char_name", "description", "first_mes", "personality"} {
+		if _, ok := top[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// v1DataKeys 返回 V1 卡片顶层中应解析到 Data 的键：规范字段以及与 data 字段同名的键
+func v1DataKeys(top map[string]json.RawMessage) []string {
+	keys := slices.Clone(v1Keys)
+	for key := range knownKeys(reflect.TypeOf(Data{})) {
+		if _, ok := top[key]; ok && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// isEmptyValue 判断 JSON 值是否为空字符串、空数组、空对象或 null
+func isEmptyValue(raw json.RawMessage) bool {
+	switch strings.TrimSpace(string(raw)) {
+	case "", `""`, "[]", "{}", "null":
+		return true
+	}
+	return false
+}
+
+func isNull(raw json.RawMessage) bool {
+	return strings.TrimSpace(string(raw)) == "null"
+}
@@ -0,0 +1,110 @@
+package card
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// 预定义错误
+var (
+	ErrInvalidBase64 = errors.New("角色卡数据不是有效的 Base64")
+	ErrInvalidJSON   = errors.New("角色卡数据不是有效的 JSON")
+	ErrNotCard       = errors.New("JSON 中没有任何角色卡字段")
+)
+
+// FieldError 描述角色卡中某个字段的错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// toFieldError 将 JSON 类型错误转换为字段错误
+func toFieldError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &FieldError{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("应为%s，实际为%s", typeName(typeErr.Type), valueName(typeErr.Value)),
+		}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	return err
+}
+
+// withFieldPrefix 为字段错误加上外层字段路径
+func withFieldPrefix(prefix string, err error) error {
+	err = toFieldError(err)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) {
+		return &FieldError{Field: prefix, Message: err.Error()}
+	}
+	field := prefix
+	if fieldErr.Field != "" {
+		field = prefix + "." + fieldErr.Field
+	}
+	return &FieldError{Field: field, Message: fieldErr.Message}
+}
+
+// typeName 返回 JSON 类型的中文名称
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return "未知类型"
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "字符串"
+	case reflect.Bool:
+		return "布尔值"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.String {
+			return "字符串数组"
+		}
+		return "数组"
+	case reflect.Map, reflect.Struct:
+		return "对象"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "整数"
+	case reflect.Float32, reflect.Float64:
+		return "数字"
+	default:
+		return strings.ToLower(t.Kind().String())
+	}
+}
+
+// valueName 返回 JSON 值种类的中文名称
+func valueName(value string) string {
+	switch value {
+	case "string":
+		return "字符串"
+	case "number":
+		return "数字"
+	case "bool":
+		return "布尔值"
+	case "array":
+		return "数组"
+	case "object":
+		return "对象"
+	default:
+		if strings.HasPrefix(value, "number") {
+			return "数字"
+		}
+		return value
+	}
+}
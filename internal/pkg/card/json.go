@@ -0,0 +1,119 @@
+package card
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// knownKeysCache 缓存各结构体类型的 JSON 键名
+var knownKeysCache sync.Map // map[reflect.Type]map[string]bool
+
+// knownKeys 返回结构体类型中声明的 JSON 键名
+func knownKeys(t reflect.Type) map[string]bool {
+	if cached, ok := knownKeysCache.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+	keys := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" || name == "" {
+			continue
+		}
+		keys[name] = true
+	}
+	knownKeysCache.Store(t, keys)
+	return keys
+}
+
+// extraFields 提取 raw 中不属于 t 类型字段的部分
+func extraFields(raw json.RawMessage, t reflect.Type) map[string]json.RawMessage {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil
+	}
+	known := knownKeys(t)
+	for key := range all {
+		if known[key] {
+			delete(all, key)
+		}
+	}
+	if len(all) == 0 {
+		return nil
+	}
+	return all
+}
+
+// remainingFields 返回去掉指定键之后的字段表
+func remainingFields(all map[string]json.RawMessage, keys ...string) map[string]json.RawMessage {
+	rest := make(map[string]json.RawMessage, len(all))
+	for k, v := range all {
+		rest[k] = v
+	}
+	for _, key := range keys {
+		delete(rest, key)
+	}
+	if len(rest) == 0 {
+		return nil
+	}
+	return rest
+}
+
+// unmarshalFields 只将字段表中指定的键解析到 data
+func unmarshalFields(all map[string]json.RawMessage, keys []string, d *Data) error {
+	subset := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		if raw, ok := all[key]; ok {
+			subset[key] = raw
+		}
+	}
+	raw, err := json.Marshal(subset)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, d); err != nil {
+		return toFieldError(err)
+	}
+	return nil
+}
+
+// unmarshalWithExtra 解析结构体并把未识别的字段存入 extra
+func unmarshalWithExtra(raw []byte, v any, extra *map[string]json.RawMessage) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return toFieldError(err)
+	}
+	*extra = extraFields(raw, reflect.TypeOf(v).Elem())
+	return nil
+}
+
+// fieldsWithExtra 将结构体展开为字段表，并合并未识别的字段
+func fieldsWithExtra(v any, extra map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for k, val := range extra {
+		if _, ok := fields[k]; !ok {
+			fields[k] = val
+		}
+	}
+	return fields, nil
+}
+
+// marshalWithExtra 序列化结构体并写回未识别的字段
+func marshalWithExtra(v any, extra map[string]json.RawMessage) ([]byte, error) {
+	if len(extra) == 0 {
+		return json.Marshal(v)
+	}
+	fields, err := fieldsWithExtra(v, extra)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
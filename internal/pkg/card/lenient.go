@@ -0,0 +1,221 @@
+package card
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fieldTypesCache 缓存各结构体类型的 JSON 键名到字段类型的映射
+var fieldTypesCache sync.Map // map[reflect.Type]map[string]reflect.Type
+
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// fieldTypes 返回结构体类型中 JSON 键名对应的字段类型
+func fieldTypes(t reflect.Type) map[string]reflect.Type {
+	if cached, ok := fieldTypesCache.Load(t); ok {
+		return cached.(map[string]reflect.Type)
+	}
+	types := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || name == "" {
+			continue
+		}
+		types[name] = field.Type
+	}
+	fieldTypesCache.Store(t, types)
+	return types
+}
+
+// normalizeCard 将社区卡片中常见的类型偏差（数字版本号、浮点时间戳、
+// 字符串形式的标签等）转换为规范类型，使整张卡片能够解析
+func normalizeCard(raw []byte) ([]byte, bool) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &top); err != nil || top == nil {
+		return nil, false
+	}
+	for _, key := range []string{"spec", "spec_version"} {
+		if value, ok := top[key]; ok {
+			top[key] = normalizeValue(value, reflect.TypeOf(""))
+		}
+	}
+	dataType := reflect.TypeOf(Data{})
+	if rawData, ok := top["data"]; ok {
+		top["data"] = normalizeValue(rawData, dataType)
+	} else {
+		// V1 卡片的字段位于顶层
+		types := fieldTypes(dataType)
+		for key, value := range top {
+			if t, ok := types[key]; ok {
+				top[key] = normalizeValue(value, t)
+			}
+		}
+	}
+	for key, value := range top {
+		if value == nil {
+			delete(top, key)
+		}
+	}
+	out, err := json.Marshal(top)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// normalizeValue 按目标类型宽松地转换 JSON 值，无法转换时返回 nil 表示丢弃该值
+func normalizeValue(raw json.RawMessage, t reflect.Type) json.RawMessage {
+	text := strings.TrimSpace(string(raw))
+	if text == "" {
+		return nil
+	}
+	if text == "null" || t == rawMessageType {
+		return raw
+	}
+	kind := jsonKind(text)
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return normalizeValue(raw, t.Elem())
+	case reflect.String:
+		switch kind {
+		case '"':
+			return raw
+		case '0', 't', 'f':
+			return mustMarshal(text)
+		}
+	case reflect.Bool:
+		switch kind {
+		case 't', 'f':
+			return raw
+		case '0':
+			n, err := strconv.ParseFloat(text, 64)
+			if err == nil {
+				return mustMarshal(n != 0)
+			}
+		case '"':
+			var s string
+			if json.Unmarshal(raw, &s) == nil {
+				if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+					return mustMarshal(b)
+				}
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, ok := parseNumber(raw, kind); ok && !math.IsInf(n, 0) && !math.IsNaN(n) {
+			if _, err := strconv.ParseInt(text, 10, 64); err == nil {
+				return raw
+			}
+			return mustMarshal(int64(n))
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := parseNumber(raw, kind); ok {
+			return mustMarshal(n)
+		}
+	case reflect.Slice:
+		switch kind {
+		case '[':
+			var items []json.RawMessage
+			if json.Unmarshal(raw, &items) != nil {
+				return nil
+			}
+			out := make([]json.RawMessage, 0, len(items))
+			for _, item := range items {
+				if v := normalizeValue(item, t.Elem()); v != nil {
+					out = append(out, v)
+				}
+			}
+			return mustMarshal(out)
+		case '"':
+			// 单个字符串写在了数组字段上，如 "tags": "x"
+			if t.Elem().Kind() == reflect.String {
+				return mustMarshal([]json.RawMessage{raw})
+			}
+		}
+	case reflect.Map:
+		if kind != '{' {
+			return nil
+		}
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(raw, &fields) != nil {
+			return nil
+		}
+		for key, value := range fields {
+			if v := normalizeValue(value, t.Elem()); v != nil {
+				fields[key] = v
+			} else {
+				delete(fields, key)
+			}
+		}
+		return mustMarshal(fields)
+	case reflect.Struct:
+		if kind != '{' {
+			return nil
+		}
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(raw, &fields) != nil {
+			return nil
+		}
+		types := fieldTypes(t)
+		for key, value := range fields {
+			ft, ok := types[key]
+			if !ok {
+				continue
+			}
+			if v := normalizeValue(value, ft); v != nil {
+				fields[key] = v
+			} else {
+				delete(fields, key)
+			}
+		}
+		return mustMarshal(fields)
+	default:
+		return raw
+	}
+	return nil
+}
+
+// jsonKind 返回 JSON 值的种类：'"' 字符串、'{' 对象、'[' 数组、't'/'f' 布尔值、'0' 数字
+func jsonKind(text string) byte {
+	switch text[0] {
+	case '"', '{', '[':
+		return text[0]
+	case 't':
+		return 't'
+	case 'f':
+		return 'f'
+	default:
+		return '0'
+	}
+}
+
+// parseNumber 解析数字或写成字符串的数字
+func parseNumber(raw json.RawMessage, kind byte) (float64, bool) {
+	switch kind {
+	case '0':
+		n, err := strconv.ParseFloat(strings.TrimSpace(string(raw)), 64)
+		return n, err == nil
+	case '"':
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			return 0, false
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func mustMarshal(v any) json.RawMessage {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
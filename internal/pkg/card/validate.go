@@ -0,0 +1,116 @@
+package card
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationError 角色卡校验失败，包含所有字段错误
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for i := range e.Errors {
+		messages = append(messages, e.Errors[i].Error())
+	}
+	return "角色卡校验失败: " + strings.Join(messages, "; ")
+}
+
+// FieldErrors 从解析或校验错误中提取字段错误列表
+func FieldErrors(err error) []FieldError {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Errors
+	}
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		return []FieldError{*fieldErr}
+	}
+	if err != nil {
+		return []FieldError{{Message: err.Error()}}
+	}
+	return nil
+}
+
+// Validate 按规范校验角色卡，返回 *ValidationError
+func (c *Card) Validate() error {
+	var errs []FieldError
+	add := func(field, format string, args ...any) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	prefix := "data."
+	if c.Version() == V1 {
+		prefix = ""
+	}
+
+	switch c.Version() {
+	case V2:
+		if c.Spec == SpecV2 && c.SpecVersion != "" && c.SpecVersion != SpecVersionV2 {
+			add("spec_version", "V2 卡片的 spec_version 应为 %q，实际为 %q", SpecVersionV2, c.SpecVersion)
+		}
+	case V3:
+		if !strings.HasPrefix(c.SpecVersion, "3.") {
+			add("spec_version", "V3 卡片的 spec_version 应为 3.x，实际为 %q", c.SpecVersion)
+		}
+	}
+
+	if c.Name() == "" {
+		add(prefix+"name", "角色名称不能为空")
+	}
+
+	if book := c.Data.CharacterBook; book != nil {
+		for i, entry := range book.Entries {
+			field := fmt.Sprintf("data.character_book.entries.%d", i)
+			if entry.Content == "" && len(entry.Keys) == 0 {
+				add(field, "条目既没有关键字也没有内容")
+			}
+			if entry.Position != "" && entry.Position != "before_char" && entry.Position != "after_char" {
+				add(field+".position", "应为 before_char 或 after_char，实际为 %q", entry.Position)
+			}
+		}
+	}
+
+	for i, asset := range c.Data.Assets {
+		field := fmt.Sprintf("data.assets.%d", i)
+		if asset.Type == "" {
+			add(field+".type", "资源类型不能为空")
+		}
+		if asset.URI == "" {
+			add(field+".uri", "资源 URI 不能为空")
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// ParseAndValidate 解析角色卡 JSON 并校验，失败时返回的错误可交给 FieldErrors
+func ParseAndValidate(raw []byte) (*Card, error) {
+	c, err := ParseStrict(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// EncodeChunks 生成写入 PNG 的 'chara'（V2 降级）和 'ccv3'（V3）数据
+func (c *Card) EncodeChunks() (string, string, error) {
+	charaV2, err := c.ToV2().Encode()
+	if err != nil {
+		return "", "", fmt.Errorf("序列化 V2 数据失败: %w", err)
+	}
+	charaV3, err := c.ToV3().Encode()
+	if err != nil {
+		return "", "", fmt.Errorf("序列化 V3 数据失败: %w", err)
+	}
+	return charaV2, charaV3, nil
+}
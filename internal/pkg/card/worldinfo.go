@@ -0,0 +1,348 @@
+package card
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// WorldInfo SillyTavern 的独立世界书（World Info）文件
+//
+// 条目以 uid 字符串为键，字段名与角色卡内嵌世界书不同，且大量设置存放在
+// 条目顶层；转换时按 SillyTavern 的规则与 character_book 条目的 extensions 互相映射。
+type WorldInfo struct {
+	Entries map[string]WorldInfoEntry `json:"entries"`
+	// OriginalData 导出时附带的原始 character_book，便于回写时保留世界书级别的设置
+	OriginalData *CharacterBook `json:"originalData,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// WorldInfoEntry World Info 条目，保留原始 JSON 字段以免丢失未识别的设置
+type WorldInfoEntry map[string]json.RawMessage
+
+// worldInfoField character_book 条目扩展字段与 World Info 条目字段的对应关系
+type worldInfoField struct {
+	extension string
+	field     string
+	// fallback 扩展字段缺失时 World Info 中的默认值，空串表示需要特殊计算
+	fallback string
+}
+
+// worldInfoFields 与 SillyTavern 的 convertCharacterBook 保持一致
+var worldInfoFields = []worldInfoField{
+	{"position", "position", ""},
+	{"display_index", "displayIndex", ""},
+	{"case_sensitive", "caseSensitive", ""},
+	{"exclude_recursion", "excludeRecursion", "false"},
+	{"prevent_recursion", "preventRecursion", "false"},
+	{"delay_until_recursion", "delayUntilRecursion", "false"},
+	{"probability", "probability", "100"},
+	{"useProbability", "useProbability", "true"},
+	{"depth", "depth", "4"},
+	{"selectiveLogic", "selectiveLogic", "0"},
+	{"group", "group", `""`},
+	{"group_override", "groupOverride", "false"},
+	{"group_weight", "groupWeight", "100"},
+	{"scan_depth", "scanDepth", "null"},
+	{"match_whole_words", "matchWholeWords", "null"},
+	{"use_group_scoring", "useGroupScoring", "null"},
+	{"automation_id", "automationId", `""`},
+	{"role", "role", "null"},
+	{"vectorized", "vectorized", "false"},
+	{"sticky", "sticky", "null"},
+	{"cooldown", "cooldown", "null"},
+	{"delay", "delay", "null"},
+}
+
+// worldInfoBaseFields 在条目顶层直接映射、不经过 extensions 的 World Info 字段
+var worldInfoBaseFields = []string{
+	"uid", "key", "keysecondary", "comment", "content", "constant",
+	"selective", "order", "disable", "addMemo", "extensions", "use_regex",
+}
+
+// World Info 中 position 字段的取值：0 为角色定义之前，1 为之后
+const (
+	worldInfoBefore = 0
+	worldInfoAfter  = 1
+)
+
+// ParseWorldInfo 解析 SillyTavern World Info JSON
+func ParseWorldInfo(raw []byte) (*WorldInfo, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &top); err != nil || top == nil {
+		return nil, fmt.Errorf("%w: 世界书必须是 JSON 对象", ErrInvalidJSON)
+	}
+	rawEntries, ok := top["entries"]
+	if !ok {
+		return nil, &FieldError{Field: "entries", Message: "缺少 entries 字段"}
+	}
+
+	var w WorldInfo
+	if err := json.Unmarshal(rawEntries, &w.Entries); err != nil {
+		return nil, &FieldError{Field: "entries", Message: "应为以 uid 为键的对象"}
+	}
+	if rawOriginal, ok := top["originalData"]; ok && !isNull(rawOriginal) {
+		if book, err := ParseCharacterBook(rawOriginal); err == nil {
+			w.OriginalData = book
+		}
+	}
+	w.Extra = remainingFields(top, "entries", "originalData")
+	return &w, nil
+}
+
+// ParseLorebook 解析世界书文件，同时接受 World Info 和 character_book 两种格式
+func ParseLorebook(raw []byte) (*CharacterBook, error) {
+	var probe struct {
+		Entries json.RawMessage `json:"entries"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	var entries []json.RawMessage
+	if json.Unmarshal(probe.Entries, &entries) == nil {
+		return ParseCharacterBook(raw)
+	}
+
+	w, err := ParseWorldInfo(raw)
+	if err != nil {
+		return nil, err
+	}
+	return w.CharacterBook()
+}
+
+// MarshalJSON 序列化 World Info 并写回未识别的字段
+func (w WorldInfo) MarshalJSON() ([]byte, error) {
+	type plain WorldInfo
+	if w.Entries == nil {
+		w.Entries = map[string]WorldInfoEntry{}
+	}
+	return marshalWithExtra(plain(w), w.Extra)
+}
+
+// WorldInfo 将内嵌世界书转换为 SillyTavern World Info
+//
+// 条目的 uid 取自 id，没有数字 id 时取下标；uid 重复时（id 重复，或有无 id
+// 的条目混在一起）后出现的条目改用下一个空闲的 uid，以免互相覆盖。
+func (b *CharacterBook) WorldInfo() *WorldInfo {
+	w := &WorldInfo{
+		Entries:      make(map[string]WorldInfoEntry, len(b.Entries)),
+		OriginalData: b,
+	}
+	used := make(map[int]bool, len(b.Entries))
+	for i, entry := range b.Entries {
+		uid := i
+		if len(entry.ID) > 0 {
+			var id int
+			if err := json.Unmarshal(entry.ID, &id); err == nil {
+				uid = id
+			}
+		}
+		for used[uid] {
+			uid++
+		}
+		used[uid] = true
+		w.Entries[strconv.Itoa(uid)] = entry.worldInfoEntry(uid, i)
+	}
+	return w
+}
+
+// worldInfoEntry 将单个条目转换为 World Info 条目
+func (e BookEntry) worldInfoEntry(uid, index int) WorldInfoEntry {
+	out := WorldInfoEntry{}
+	set := func(key string, value interface{}) {
+		out[key], _ = json.Marshal(value)
+	}
+
+	keys, secondaryKeys := e.Keys, e.SecondaryKeys
+	if keys == nil {
+		keys = []string{}
+	}
+	if secondaryKeys == nil {
+		secondaryKeys = []string{}
+	}
+	set("uid", uid)
+	set("key", keys)
+	set("keysecondary", secondaryKeys)
+	set("comment", e.Comment)
+	set("content", e.Content)
+	set("constant", e.Constant != nil && *e.Constant)
+	set("selective", e.Selective != nil && *e.Selective)
+	set("order", e.InsertionOrder)
+	set("disable", !e.Enabled)
+	set("addMemo", e.Comment != "")
+	if e.UseRegex != nil {
+		set("use_regex", *e.UseRegex)
+	}
+
+	for _, f := range worldInfoFields {
+		if value, ok := e.Extensions[f.extension]; ok && !isNull(value) {
+			out[f.field] = value
+			continue
+		}
+		switch f.field {
+		case "position":
+			if e.Position == "before_char" {
+				set(f.field, worldInfoBefore)
+			} else {
+				set(f.field, worldInfoAfter)
+			}
+		case "displayIndex":
+			set(f.field, index)
+		case "caseSensitive":
+			if e.CaseSensitive != nil {
+				set(f.field, *e.CaseSensitive)
+			} else {
+				out[f.field] = json.RawMessage("null")
+			}
+		default:
+			out[f.field] = json.RawMessage(f.fallback)
+		}
+	}
+
+	// 其余扩展字段原样放回条目顶层，与 CharacterBook 的转换互逆
+	for key, value := range e.Extensions {
+		if _, exists := out[key]; !exists && !isWorldInfoExtension(key) {
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// CharacterBook 将 World Info 转换为角色卡内嵌世界书，条目按 uid 排序
+func (w *WorldInfo) CharacterBook() (*CharacterBook, error) {
+	book := &CharacterBook{Extensions: Extensions{}}
+	if w.OriginalData != nil {
+		book.Name = w.OriginalData.Name
+		book.Description = w.OriginalData.Description
+		book.ScanDepth = w.OriginalData.ScanDepth
+		book.TokenBudget = w.OriginalData.TokenBudget
+		book.RecursiveScanning = w.OriginalData.RecursiveScanning
+		if w.OriginalData.Extensions != nil {
+			book.Extensions = w.OriginalData.Extensions
+		}
+		book.Extra = w.OriginalData.Extra
+	}
+
+	keys := make([]string, 0, len(w.Entries))
+	for key := range w.Entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		if (errA == nil) != (errB == nil) {
+			return errA == nil
+		}
+		return keys[i] < keys[j]
+	})
+
+	book.Entries = make([]BookEntry, 0, len(keys))
+	for _, key := range keys {
+		entry, err := w.Entries[key].bookEntry()
+		if err != nil {
+			return nil, withFieldPrefix("entries."+key, err)
+		}
+		book.Entries = append(book.Entries, entry)
+	}
+	return book, nil
+}
+
+// bookEntry 将 World Info 条目转换为角色卡世界书条目
+func (we WorldInfoEntry) bookEntry() (BookEntry, error) {
+	var e BookEntry
+	fields := []struct {
+		key    string
+		target interface{}
+	}{
+		{"key", &e.Keys},
+		{"keysecondary", &e.SecondaryKeys},
+		{"comment", &e.Comment},
+		{"content", &e.Content},
+		{"constant", &e.Constant},
+		{"selective", &e.Selective},
+		{"order", &e.InsertionOrder},
+	}
+	for _, f := range fields {
+		value, ok := we[f.key]
+		if !ok || isNull(value) {
+			continue
+		}
+		if err := json.Unmarshal(value, f.target); err != nil {
+			return e, withFieldPrefix(f.key, toFieldError(err))
+		}
+	}
+
+	var disable bool
+	if value, ok := we["disable"]; ok {
+		json.Unmarshal(value, &disable)
+	}
+	e.Enabled = !disable
+	if value, ok := we["uid"]; ok && !isNull(value) {
+		e.ID = value
+	}
+	var useRegex bool
+	if value, ok := we["use_regex"]; ok && !isNull(value) && json.Unmarshal(value, &useRegex) == nil {
+		e.UseRegex = &useRegex
+	}
+
+	e.Extensions = Extensions{}
+	if value, ok := we["extensions"]; ok {
+		json.Unmarshal(value, &e.Extensions)
+	}
+	for _, f := range worldInfoFields {
+		if value, ok := we[f.field]; ok {
+			e.Extensions[f.extension] = value
+		}
+	}
+
+	var position int
+	if value, ok := we["position"]; ok {
+		json.Unmarshal(value, &position)
+	}
+	if position == worldInfoBefore {
+		e.Position = "before_char"
+	} else {
+		e.Position = "after_char"
+	}
+	var caseSensitive bool
+	if value, ok := we["caseSensitive"]; ok && json.Unmarshal(value, &caseSensitive) == nil && !isNull(value) {
+		e.CaseSensitive = &caseSensitive
+	}
+
+	for key, value := range we {
+		if !isWorldInfoField(key) {
+			e.Extensions[key] = value
+		}
+	}
+	return e, nil
+}
+
+// isWorldInfoField 判断是否为已映射的 World Info 条目字段
+func isWorldInfoField(key string) bool {
+	for _, field := range worldInfoBaseFields {
+		if field == key {
+			return true
+		}
+	}
+	for _, f := range worldInfoFields {
+		if f.field == key {
+			return true
+		}
+	}
+	return false
+}
+
+// isWorldInfoExtension 判断是否为已映射的世界书扩展字段
+func isWorldInfoExtension(key string) bool {
+	for _, f := range worldInfoFields {
+		if f.extension == key {
+			return true
+		}
+	}
+	return false
+}
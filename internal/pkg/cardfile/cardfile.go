@@ -0,0 +1,251 @@
+package cardfile
+
+import (
+	"bytes"
+	"card-manager/internal/pkg/card"
+	"card-manager/internal/pkg/charx"
+	"card-manager/internal/pkg/imaging"
+	"card-manager/internal/pkg/png"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsCardFile 判断文件是否为角色卡版本文件（PNG 或 CHARX）
+func IsCardFile(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	return ext == ".png" || ext == charx.Extension
+}
+
+// IsCharx 判断文件是否为 CHARX 角色卡包
+func IsCharx(fileName string) bool {
+	return strings.ToLower(filepath.Ext(fileName)) == charx.Extension
+}
+
+// Load 读取版本文件中的角色卡，PNG 读取 ccv3/chara 块，CHARX 读取 card.json
+func Load(filePath string) (*card.Card, error) {
+	raw, err := LoadJSON(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return card.Parse(raw)
+}
+
+// LoadJSON 读取版本文件中角色卡的原始 JSON，不要求其能按规范解析
+//
+// 导出 JSON 正是为了手工修复有问题的卡片，因此这里只做 Base64 解码，
+// 解析交给调用方。
+func LoadJSON(filePath string) ([]byte, error) {
+	if IsCharx(filePath) {
+		return charx.ReadCardJSON(filePath)
+	}
+	encoded, err := png.GetCharacterData(filePath)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", card.ErrInvalidBase64, err)
+	}
+	return raw, nil
+}
+
+// PrettyJSON 返回格式化后的角色卡 JSON，保留原有字段顺序
+//
+// 数据不是合法 JSON 时原样返回，便于用户手工修复。
+func PrettyJSON(filePath string) ([]byte, error) {
+	raw, err := LoadJSON(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return raw, nil
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// JSONFileName 返回版本文件对应的 JSON 文件名
+func JSONFileName(filePath string) string {
+	return strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)) + ".json"
+}
+
+// ExportJSON 将版本文件中的角色卡导出为格式化的 JSON 文件
+func ExportJSON(filePath, outputPath string) error {
+	data, err := PrettyJSON(filePath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, data, 0644)
+}
+
+// Save 将修改后的角色卡写入 outputPath，图片和其他内容沿用 srcPath 的版本文件
+//
+// PNG 重写 chara 和 ccv3 数据块并保留内嵌资源块，CHARX 替换包内的 card.json。
+// outputPath 可以与 srcPath 相同。
+func Save(srcPath, outputPath string, c *card.Card) error {
+	if IsCharx(srcPath) {
+		return charx.Rewrite(srcPath, outputPath, c)
+	}
+	charaV2, charaV3, err := c.EncodeChunks()
+	if err != nil {
+		return err
+	}
+	return png.WriteCharacterData(srcPath, outputPath, charaV2, charaV3)
+}
+
+// ReplaceAvatar 用新图片替换版本的头像，写出保留原角色卡数据的 PNG 版本
+//
+// 新图片可以是 PNG、JPEG、GIF 或 WebP，非 PNG 会重新编码。PNG 版本原样
+// 保留 chara、ccv3 和内嵌资源块；CHARX 版本则由 card.json 生成 chara 和 ccv3。
+func ReplaceAvatar(versionPath string, imageData []byte, outputPath string) error {
+	var texts []png.TextChunk
+	if IsCharx(versionPath) {
+		parsed, err := Load(versionPath)
+		if err != nil {
+			return err
+		}
+		charaV2, charaV3, err := parsed.EncodeChunks()
+		if err != nil {
+			return err
+		}
+		texts = []png.TextChunk{
+			{Type: png.TypeText, Keyword: png.KeywordChara, Text: charaV2},
+			{Type: png.TypeText, Keyword: png.KeywordCCv3, Text: charaV3},
+		}
+	} else {
+		allTexts, err := png.ReadTextChunks(versionPath)
+		if err != nil {
+			return err
+		}
+		if _, _, err := png.CharacterData(allTexts); err != nil {
+			return err
+		}
+		texts = png.CharacterTextChunks(allTexts)
+	}
+
+	pngData, err := imaging.ToPNG(imageData)
+	if err != nil {
+		return err
+	}
+	// 新图片自身可能也是角色卡，先去掉它的角色数据
+	pngData, err = png.StripCharacterData(pngData)
+	if err != nil {
+		return err
+	}
+	return png.WriteImageWithTextChunks(pngData, outputPath, texts...)
+}
+
+// 角色卡数据的完整性问题，PNG 结构问题见 png.Problem* 常量
+const (
+	ProblemNoCharaChunk  = "no chara chunk"
+	ProblemInvalidBase64 = "invalid base64"
+	ProblemInvalidJSON   = "invalid JSON"
+	ProblemInvalidCard   = "invalid card data"
+	ProblemBadArchive    = "corrupt archive"
+	ProblemNoCardJSON    = "no card.json"
+)
+
+// Info 单次读取版本文件得到的哈希、角色卡和完整性问题
+type Info struct {
+	Hash     string
+	Card     *card.Card
+	Problems []string
+}
+
+// Inspect 读取版本文件的哈希、角色卡和完整性问题
+//
+// PNG 只读取一遍：计算哈希的同时校验签名、块 CRC 和 IEND（CRC 不符的文本块
+// 仍会解码），并确认角色卡数据能按 Base64 和 JSON 解码；CHARX 确认压缩包
+// 可读且 card.json 有效。字段类型偏差由 card.Parse 宽松处理，不算作问题。
+// 仅在无法读取文件时返回错误，Card 在数据无效时为 nil。
+func Inspect(filePath string) (*Info, error) {
+	if IsCharx(filePath) {
+		return inspectCharx(filePath)
+	}
+
+	result, err := png.ScanFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{Hash: result.Hash, Problems: result.Problems}
+	if len(info.Problems) > 0 && info.Problems[0] == png.ProblemBadSignature {
+		return info, nil
+	}
+
+	encoded, _, err := png.CharacterData(result.Texts)
+	if err != nil {
+		info.Problems = append(info.Problems, ProblemNoCharaChunk)
+		return info, nil
+	}
+	parsed, err := card.Decode(encoded)
+	switch {
+	case err == nil:
+		info.Card = parsed
+	case errors.Is(err, card.ErrInvalidBase64):
+		info.Problems = append(info.Problems, ProblemInvalidBase64)
+	case errors.Is(err, card.ErrInvalidJSON):
+		info.Problems = append(info.Problems, ProblemInvalidJSON)
+	default:
+		info.Problems = append(info.Problems, ProblemInvalidCard)
+	}
+	return info, nil
+}
+
+// inspectCharx 计算 CHARX 文件的哈希并检查其中的 card.json
+func inspectCharx(filePath string) (*Info, error) {
+	hash, err := FileHash(filePath)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{Hash: hash}
+
+	pkg, err := charx.Open(filePath)
+	if err != nil {
+		switch {
+		case errors.Is(err, charx.ErrNoCardJSON):
+			info.Problems = []string{ProblemNoCardJSON}
+		case errors.Is(err, card.ErrInvalidJSON):
+			info.Problems = []string{ProblemInvalidJSON}
+		case errors.Is(err, charx.ErrInvalidCard):
+			info.Problems = []string{ProblemInvalidCard}
+		default:
+			info.Problems = []string{ProblemBadArchive}
+		}
+		return info, nil
+	}
+	defer pkg.Close()
+	info.Card = pkg.Card
+	return info, nil
+}
+
+// FileHash 计算文件的 SHA-256 哈希
+func FileHash(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Avatar 读取版本文件的头像图片，PNG 为文件本身，CHARX 为包内的头像资源
+func Avatar(filePath string) ([]byte, error) {
+	if IsCharx(filePath) {
+		return charx.ReadAvatar(filePath)
+	}
+	return os.ReadFile(filePath)
+}
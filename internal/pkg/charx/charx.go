@@ -0,0 +1,457 @@
+package charx
+
+import (
+	"archive/zip"
+	"bytes"
+	"card-manager/internal/pkg/card"
+	"card-manager/internal/pkg/imaging"
+	"card-manager/internal/pkg/png"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Extension CHARX 文件扩展名
+	Extension = ".charx"
+	// CardFileName 包内角色卡 JSON 的文件名
+	CardFileName = "card.json"
+	// URIPrefix 包内资源的 URI 前缀（规范中的拼写就是 embeded）
+	URIPrefix = "embeded://"
+
+	// 兼容部分工具使用的正确拼写
+	uriPrefixAlt = "embedded://"
+	// PNG 中 ccdefault: 表示图片本身，__asset: 表示内嵌资源块
+	uriDefault  = "ccdefault:"
+	uriPNGAsset = "__asset:"
+
+	mainIconPath = "assets/icon/image/main.png"
+)
+
+// 预定义错误
+var (
+	ErrNoCardJSON     = errors.New("CHARX 中缺少 card.json")
+	ErrInvalidCard    = errors.New("解析 card.json 失败")
+	ErrNoAvatar       = errors.New("CHARX 中没有可用的头像图片")
+	ErrAssetNotFound  = errors.New("CHARX 中找不到资源")
+	ErrUnsupportedURI = errors.New("不支持的资源 URI")
+)
+
+// Package 已打开的 CHARX 角色卡包
+type Package struct {
+	Card   *card.Card
+	reader *zip.ReadCloser
+	files  map[string]*zip.File
+}
+
+// Open 打开 CHARX 文件并解析其中的 card.json
+func Open(filePath string) (*Package, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开 CHARX 文件: %w", err)
+	}
+
+	pkg := &Package{reader: reader, files: make(map[string]*zip.File, len(reader.File))}
+	for _, f := range reader.File {
+		pkg.files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	raw, err := pkg.ReadFile(CardFileName)
+	if err != nil {
+		reader.Close()
+		if errors.Is(err, ErrAssetNotFound) {
+			return nil, ErrNoCardJSON
+		}
+		return nil, err
+	}
+	pkg.Card, err = card.Parse(raw)
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCard, err)
+	}
+	return pkg, nil
+}
+
+// Close 关闭 CHARX 文件
+func (p *Package) Close() error {
+	return p.reader.Close()
+}
+
+// ReadFile 读取包内文件
+func (p *Package) ReadFile(name string) ([]byte, error) {
+	f, ok := p.files[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// ResolveURI 读取 embeded:// URI 指向的包内资源
+func (p *Package) ResolveURI(uri string) ([]byte, error) {
+	name, ok := archivePath(uri)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedURI, uri)
+	}
+	return p.ReadFile(name)
+}
+
+// Avatar 返回角色头像图片的原始数据
+func (p *Package) Avatar() ([]byte, error) {
+	_, data, err := p.avatar()
+	return data, err
+}
+
+// avatar 查找头像：优先 name 为 main 的 icon 资源，其次任意 icon 资源，
+// 最后退回到包内的第一张图片。返回资源下标，退回时为 -1。
+func (p *Package) avatar() (int, []byte, error) {
+	candidates := make([]int, 0, 1)
+	for i, asset := range p.Card.Data.Assets {
+		if asset.Type == "icon" && asset.Name == "main" {
+			candidates = append([]int{i}, candidates...)
+		} else if asset.Type == "icon" {
+			candidates = append(candidates, i)
+		}
+	}
+	for _, i := range candidates {
+		if data, err := p.ResolveURI(p.Card.Data.Assets[i].URI); err == nil {
+			return i, data, nil
+		}
+	}
+
+	for _, f := range p.reader.File {
+		if f.FileInfo().IsDir() || !isImageName(f.Name) {
+			continue
+		}
+		if data, err := p.ReadFile(f.Name); err == nil && imaging.Extension(data) != "" {
+			return -1, data, nil
+		}
+	}
+	return -1, nil, ErrNoAvatar
+}
+
+// ReadCard 读取 CHARX 文件中的角色卡
+func ReadCard(filePath string) (*card.Card, error) {
+	pkg, err := Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer pkg.Close()
+	return pkg.Card, nil
+}
+
+// ReadCardJSON 读取 CHARX 文件中 card.json 的原始内容，不解析角色卡
+func ReadCardJSON(filePath string) ([]byte, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开 CHARX 文件: %w", err)
+	}
+	defer reader.Close()
+
+	for _, f := range reader.File {
+		if strings.TrimPrefix(f.Name, "/") != CardFileName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, ErrNoCardJSON
+}
+
+// ReadAvatar 读取 CHARX 文件中的头像图片
+func ReadAvatar(filePath string) ([]byte, error) {
+	pkg, err := Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer pkg.Close()
+	return pkg.Avatar()
+}
+
+// ReadAsset 读取 CHARX 文件中 URI 指向的资源
+func ReadAsset(filePath, uri string) ([]byte, error) {
+	pkg, err := Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer pkg.Close()
+	return pkg.ResolveURI(uri)
+}
+
+// ToPNG 将 CHARX 转换为 PNG 角色卡
+//
+// 头像作为图片本体（非 PNG 时重新编码），其余内嵌资源按 V3 规范写入
+// chara-ext-asset_ 文本块，并同时写入 chara 和 ccv3 数据块。
+func ToPNG(charxPath, outputPath string) error {
+	pkg, err := Open(charxPath)
+	if err != nil {
+		return err
+	}
+	defer pkg.Close()
+
+	avatarIndex, avatarData, err := pkg.avatar()
+	if err != nil {
+		return err
+	}
+	imageData, err := imaging.ToPNG(avatarData)
+	if err != nil {
+		return err
+	}
+	imageData, err = png.StripCharacterData(imageData)
+	if err != nil {
+		return err
+	}
+
+	v3 := pkg.Card.ToV3()
+	v3.Data.Assets = make([]card.Asset, len(pkg.Card.Data.Assets))
+	copy(v3.Data.Assets, pkg.Card.Data.Assets)
+
+	var texts []png.TextChunk
+	for i := range v3.Data.Assets {
+		asset := &v3.Data.Assets[i]
+		if i == avatarIndex {
+			asset.URI = uriDefault
+			asset.Ext = "png"
+			continue
+		}
+		if _, ok := archivePath(asset.URI); !ok {
+			continue
+		}
+		data, err := pkg.ResolveURI(asset.URI)
+		if err != nil {
+			return err
+		}
+		key := strconv.Itoa(len(texts))
+		texts = append(texts, png.TextChunk{
+			Type:    png.TypeText,
+			Keyword: png.KeywordAssetPrefix + key,
+			Text:    base64.StdEncoding.EncodeToString(data),
+		})
+		asset.URI = uriPNGAsset + key
+	}
+
+	charaV2, charaV3, err := v3.EncodeChunks()
+	if err != nil {
+		return err
+	}
+	texts = append(texts,
+		png.TextChunk{Type: png.TypeText, Keyword: png.KeywordChara, Text: charaV2},
+		png.TextChunk{Type: png.TypeText, Keyword: png.KeywordCCv3, Text: charaV3},
+	)
+	return png.WriteImageWithTextChunks(imageData, outputPath, texts...)
+}
+
+// FromPNG 将 PNG 角色卡转换为 CHARX
+//
+// PNG 图片本身作为 main 头像，chara-ext-asset_ 文本块中的资源解出为包内文件。
+func FromPNG(pngPath, outputPath string) error {
+	texts, err := png.ReadTextChunks(pngPath)
+	if err != nil {
+		return err
+	}
+	encoded, _, err := png.CharacterData(texts)
+	if err != nil {
+		return err
+	}
+	parsed, err := card.Decode(encoded)
+	if err != nil {
+		return err
+	}
+
+	imageData, err := os.ReadFile(pngPath)
+	if err != nil {
+		return err
+	}
+	imageData, err = png.StripCharacterData(imageData)
+	if err != nil {
+		return err
+	}
+
+	pngAssets := make(map[string]string)
+	for _, tc := range texts {
+		if key, ok := strings.CutPrefix(tc.Keyword, png.KeywordAssetPrefix); ok {
+			pngAssets[key] = tc.Text
+		}
+	}
+
+	files := map[string][]byte{mainIconPath: imageData}
+	used := map[string]bool{mainIconPath: true}
+	mainIcon := card.Asset{Type: "icon", URI: URIPrefix + mainIconPath, Name: "main", Ext: "png"}
+
+	v3 := parsed.ToV3()
+	assets := make([]card.Asset, 0, len(parsed.Data.Assets)+1)
+	hasIcon := false
+	for _, asset := range parsed.Data.Assets {
+		switch {
+		case asset.URI == uriDefault && asset.Type == "icon" && !hasIcon:
+			mainIcon.Name, mainIcon.Extra = asset.Name, asset.Extra
+			asset = mainIcon
+			hasIcon = true
+		case strings.HasPrefix(asset.URI, uriPNGAsset):
+			encodedAsset, ok := pngAssets[strings.TrimPrefix(asset.URI, uriPNGAsset)]
+			if !ok {
+				break
+			}
+			data, err := base64.StdEncoding.DecodeString(encodedAsset)
+			if err != nil {
+				return fmt.Errorf("解码内嵌资源 %s 失败: %w", asset.URI, err)
+			}
+			name := assetPath(asset, used)
+			files[name] = data
+			asset.URI = URIPrefix + name
+		}
+		assets = append(assets, asset)
+	}
+	if !hasIcon {
+		assets = append([]card.Asset{mainIcon}, assets...)
+	}
+	v3.Data.Assets = assets
+
+	cardJSON, err := json.Marshal(v3)
+	if err != nil {
+		return err
+	}
+	return writeZip(outputPath, cardJSON, files)
+}
+
+// Rewrite 复制 CHARX 包并用新的角色卡替换其中的 card.json，其余文件保持不变
+func Rewrite(charxPath, outputPath string, c *card.Card) error {
+	cardJSON, err := json.Marshal(c.ToV3())
+	if err != nil {
+		return err
+	}
+
+	pkg, err := Open(charxPath)
+	if err != nil {
+		return err
+	}
+	files := make(map[string][]byte, len(pkg.files))
+	for name, f := range pkg.files {
+		if name == CardFileName || f.FileInfo().IsDir() {
+			continue
+		}
+		if files[name], err = pkg.ReadFile(name); err != nil {
+			pkg.Close()
+			return err
+		}
+	}
+	// 原文件须在重命名前关闭，否则在 Windows 上无法覆盖自身
+	pkg.Close()
+	return writeZip(outputPath, cardJSON, files)
+}
+
+// writeZip 写入 CHARX 文件，先写临时文件再重命名
+func writeZip(outputPath string, cardJSON []byte, files map[string][]byte) error {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(CardFileName)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(cardJSON); err != nil {
+		return err
+	}
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, outputPath)
+}
+
+// archivePath 将 embeded:// URI 转换为包内路径
+func archivePath(uri string) (string, bool) {
+	for _, prefix := range []string{URIPrefix, uriPrefixAlt} {
+		if name, ok := strings.CutPrefix(uri, prefix); ok && name != "" {
+			return strings.TrimPrefix(path.Clean("/"+name), "/"), true
+		}
+	}
+	return "", false
+}
+
+// assetPath 按 assets/<类型>/<分类>/<名称>.<扩展名> 生成不重复的包内路径
+func assetPath(asset card.Asset, used map[string]bool) string {
+	ext := strings.TrimPrefix(strings.ToLower(asset.Ext), ".")
+	kind := "other"
+	switch ext {
+	case "png", "jpg", "jpeg", "webp", "gif", "avif":
+		kind = "image"
+	case "mp3", "wav", "ogg", "flac", "m4a":
+		kind = "audio"
+	case "mp4", "webm", "mov":
+		kind = "video"
+	}
+	assetType := sanitize(asset.Type)
+	if assetType == "" {
+		assetType = "other"
+	}
+	name := sanitize(asset.Name)
+	if name == "" {
+		name = "asset"
+	}
+	suffix := ""
+	if ext != "" {
+		suffix = "." + ext
+	}
+
+	candidate := path.Join("assets", assetType, kind, name+suffix)
+	for i := 1; used[candidate]; i++ {
+		candidate = path.Join("assets", assetType, kind, fmt.Sprintf("%s_%d%s", name, i, suffix))
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// sanitize 去掉文件名中不安全的字符
+func sanitize(name string) string {
+	r := strings.NewReplacer(`\`, "_", `/`, "_", `:`, "_", `*`, "_", `?`, "_", `"`, "_", `<`, "_", `>`, "_", `|`, "_")
+	return strings.TrimSpace(r.Replace(name))
+}
+
+// isImageName 根据扩展名判断是否为图片文件
+func isImageName(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".webp", ".gif":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+// Package collections 角色库的智能收藏夹
+//
+// 智能收藏夹是保存下来的筛选条件，不记录具体的角色；每次查看时按条件重新筛选，
+// 所以角色的导入状态、标签或评分变化后收藏夹的内容随之变化。
+package collections
+
+import (
+	"card-manager/internal/pkg/jsonfile"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// maxNameLength 收藏夹名称的最大字数
+const maxNameLength = 64
+
+// Collection 一个智能收藏夹
+type Collection struct {
+	Name string `json:"name"`
+	// Query 筛选条件，与 /api/cards 的查询参数相同（如 import=outdated&tag=fantasy&minRating=4）
+	Query string `json:"query"`
+}
+
+// File 收藏夹文件的内容，也是导出和导入使用的格式
+type File struct {
+	Collections []Collection `json:"collections"`
+}
+
+// Store 保存在角色库元数据目录中的收藏夹列表，读取后缓存在内存中
+//
+// 文件在外部被改动（如撤销操作恢复了旧文件）时重新读取。
+type Store struct {
+	file *jsonfile.Store[File]
+}
+
+// New 创建保存在 path 的收藏夹列表
+func New(path string) *Store {
+	return &Store{file: jsonfile.New(path, (*File).clone)}
+}
+
+// Path 收藏夹文件的路径
+func (s *Store) Path() string {
+	return s.file.Path()
+}
+
+// Load 返回收藏夹列表的副本
+func (s *Store) Load() ([]Collection, error) {
+	file, err := s.file.Load()
+	if err != nil {
+		return make([]Collection, 0), err
+	}
+	return file.Collections, nil
+}
+
+// Update 读取、修改并保存收藏夹列表
+func (s *Store) Update(modify func(list []Collection) ([]Collection, error)) error {
+	return s.file.Update(func(file *File) error {
+		list, err := modify(file.Collections)
+		if err != nil {
+			return err
+		}
+		file.Collections = append(make([]Collection, 0, len(list)), list...)
+		return nil
+	})
+}
+
+// clone 复制收藏夹列表，避免调用方修改缓存的内容
+func (f *File) clone() *File {
+	return &File{Collections: append(make([]Collection, 0, len(f.Collections)), f.Collections...)}
+}
+
+// NormalizeName 整理收藏夹名称：去掉首尾空白并把连续空白压成一个空格，返回不能使用的原因
+func NormalizeName(name string) (string, string) {
+	name = strings.Join(strings.Fields(name), " ")
+	switch {
+	case name == "":
+		return "", "收藏夹名称不能为空"
+	case len([]rune(name)) > maxNameLength:
+		return "", "收藏夹名称过长"
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return "", "收藏夹名称不能包含控制字符"
+	}
+	return name, ""
+}
+
+// Index 返回名称对应的收藏夹下标，不区分大小写，找不到时返回 -1
+func Index(list []Collection, name string) int {
+	for i, collection := range list {
+		if strings.EqualFold(collection.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ErrExists 收藏夹已存在
+var ErrExists = errors.New("收藏夹已存在")
+
+// ErrNotFound 收藏夹不存在
+var ErrNotFound = errors.New("收藏夹不存在")
@@ -0,0 +1,116 @@
+package diff
+
+import "strings"
+
+// Op 差异行的类型
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Line 差异结果中的一行
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines 按行比较两段文本，返回基于最长公共子序列的逐行差异
+//
+// 先去掉相同的首尾行再做动态规划，常见的局部修改只需很小的表。
+func Lines(a, b string) []Line {
+	if a == b {
+		if a == "" {
+			return nil
+		}
+		return equalLines(splitLines(a))
+	}
+	oldLines, newLines := splitLines(a), splitLines(b)
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	result := equalLines(oldLines[:prefix])
+	result = append(result, middle(oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix])...)
+	return append(result, equalLines(oldLines[len(oldLines)-suffix:])...)
+}
+
+// Changed 判断差异中是否包含新增或删除的行
+func Changed(lines []Line) bool {
+	for _, line := range lines {
+		if line.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// middle 对去掉公共首尾后的部分求最长公共子序列并回溯出差异
+func middle(a, b []string) []Line {
+	n, m := len(a), len(b)
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	result := make([]Line, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			result = append(result, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		result = append(result, Line{Op: Delete, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		result = append(result, Line{Op: Insert, Text: b[j]})
+	}
+	return result
+}
+
+// splitLines 按换行拆分文本，统一 Windows 换行，空文本没有行
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+func equalLines(lines []string) []Line {
+	result := make([]Line, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, Line{Op: Equal, Text: line})
+	}
+	return result
+}
@@ -0,0 +1,266 @@
+// Package fulltext 角色卡内容和角色备注的全文索引
+package fulltext
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+	"sync"
+)
+
+// indexVersion 索引文件的格式版本，不一致时丢弃旧索引重新建立
+const indexVersion = 1
+
+// Field 文档中的一个可搜索字段
+type Field struct {
+	Name string `json:"name"`
+	Text string `json:"text"`
+}
+
+// Document 索引中的一个文件，以文件的修改时间判断是否需要重新索引
+type Document struct {
+	Mtime  string  `json:"mtime"`
+	Fields []Field `json:"fields"`
+}
+
+// indexFile 索引文件的内容
+type indexFile struct {
+	Version   int                  `json:"version"`
+	Documents map[string]*Document `json:"documents"`
+}
+
+// Index 全文索引
+//
+// 文件中只保存各文档的字段原文，词项的倒排表在加载时重建，
+// 这样切分规则改变时不必迁移索引文件。
+type Index struct {
+	path      string
+	mutex     sync.RWMutex
+	documents map[string]*Document
+	// postings 词项 -> 文档路径 -> 词频
+	postings map[string]map[string]int
+	dirty    bool
+}
+
+// New 创建保存在 path 的全文索引
+func New(path string) *Index {
+	return &Index{
+		path:      path,
+		documents: make(map[string]*Document),
+		postings:  make(map[string]map[string]int),
+	}
+}
+
+// Load 从文件加载索引，文件不存在或格式版本不同时使用空索引
+func (ix *Index) Load() error {
+	ix.mutex.Lock()
+	defer ix.mutex.Unlock()
+
+	ix.documents = make(map[string]*Document)
+	ix.postings = make(map[string]map[string]int)
+	data, err := os.ReadFile(ix.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var file indexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	if file.Version != indexVersion {
+		return nil
+	}
+	for path, doc := range file.Documents {
+		ix.add(path, doc)
+	}
+	return nil
+}
+
+// Save 索引有改动时保存到文件
+func (ix *Index) Save() error {
+	ix.mutex.Lock()
+	defer ix.mutex.Unlock()
+	if !ix.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(indexFile{Version: indexVersion, Documents: ix.documents})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(ix.path, data, 0644); err != nil {
+		return err
+	}
+	ix.dirty = false
+	return nil
+}
+
+// Has 判断文件是否已按指定的修改时间索引
+func (ix *Index) Has(path, mtime string) bool {
+	ix.mutex.RLock()
+	defer ix.mutex.RUnlock()
+	doc, found := ix.documents[path]
+	return found && doc.Mtime == mtime
+}
+
+// Update 索引文件的内容，替换该文件原有的条目
+func (ix *Index) Update(path, mtime string, fields []Field) {
+	ix.mutex.Lock()
+	defer ix.mutex.Unlock()
+	ix.remove(path)
+	ix.add(path, &Document{Mtime: mtime, Fields: fields})
+	ix.dirty = true
+}
+
+// Prune 移除 keep 返回 false 的文档，返回移除的数量
+func (ix *Index) Prune(keep func(path string) bool) int {
+	ix.mutex.Lock()
+	defer ix.mutex.Unlock()
+	removed := 0
+	for path := range ix.documents {
+		if !keep(path) {
+			ix.remove(path)
+			removed++
+		}
+	}
+	if removed > 0 {
+		ix.dirty = true
+	}
+	return removed
+}
+
+// add 加入文档并更新倒排表，调用方需持有写锁
+func (ix *Index) add(path string, doc *Document) {
+	ix.documents[path] = doc
+	for _, field := range doc.Fields {
+		for _, term := range indexTerms(field.Text) {
+			docs := ix.postings[term]
+			if docs == nil {
+				docs = make(map[string]int)
+				ix.postings[term] = docs
+			}
+			docs[path]++
+		}
+	}
+}
+
+// remove 移除文档并清理倒排表，调用方需持有写锁
+func (ix *Index) remove(path string) {
+	doc, found := ix.documents[path]
+	if !found {
+		return
+	}
+	delete(ix.documents, path)
+	for _, field := range doc.Fields {
+		for _, term := range indexTerms(field.Text) {
+			if docs := ix.postings[term]; docs != nil {
+				delete(docs, path)
+				if len(docs) == 0 {
+					delete(ix.postings, term)
+				}
+			}
+		}
+	}
+}
+
+// Result 一组文档（如同一角色的各个版本和备注）的搜索结果
+type Result struct {
+	Key   string
+	Score float64
+	// Paths 组内命中的文档，得分高的在前
+	Paths []string
+}
+
+// Search 搜索同时包含所有词项的文档组，按相关度从高到低排序
+//
+// group 把文档路径映射为组的键，返回空串的文档不参与搜索。
+// 词项可以分别出现在组内不同的文档中；组对每个词项的得分取组内最高的文档，
+// 文档的得分为 idf × (1 + ln 词频)。
+func (ix *Index) Search(query string, group func(path string) string) []Result {
+	terms := QueryTerms(query)
+	if len(terms) == 0 {
+		return []Result{}
+	}
+
+	ix.mutex.RLock()
+	defer ix.mutex.RUnlock()
+
+	type groupScore struct {
+		termScores []float64
+		docScores  map[string]float64
+	}
+	groups := make(map[string]*groupScore)
+	total := float64(len(ix.documents))
+	for i, term := range terms {
+		docs := ix.postings[term]
+		if len(docs) == 0 {
+			return []Result{}
+		}
+		idf := math.Log(1 + total/float64(len(docs)))
+		for path, frequency := range docs {
+			key := group(path)
+			if key == "" {
+				continue
+			}
+			g := groups[key]
+			if g == nil {
+				// 第一个词项之后才出现的组不可能包含全部词项
+				if i > 0 {
+					continue
+				}
+				g = &groupScore{termScores: make([]float64, len(terms)), docScores: make(map[string]float64)}
+				groups[key] = g
+			}
+			score := idf * (1 + math.Log(float64(frequency)))
+			g.termScores[i] = math.Max(g.termScores[i], score)
+			g.docScores[path] += score
+		}
+	}
+
+	results := make([]Result, 0)
+	for key, g := range groups {
+		result := Result{Key: key}
+		complete := true
+		for _, score := range g.termScores {
+			if score == 0 {
+				complete = false
+				break
+			}
+			result.Score += score
+		}
+		if !complete {
+			continue
+		}
+		for path := range g.docScores {
+			result.Paths = append(result.Paths, path)
+		}
+		sort.Slice(result.Paths, func(i, j int) bool {
+			a, b := g.docScores[result.Paths[i]], g.docScores[result.Paths[j]]
+			if a != b {
+				return a > b
+			}
+			return result.Paths[i] < result.Paths[j]
+		})
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Key < results[j].Key
+	})
+	return results
+}
+
+// Fields 返回已索引文档的字段，用于生成摘要
+func (ix *Index) Fields(path string) []Field {
+	ix.mutex.RLock()
+	defer ix.mutex.RUnlock()
+	if doc, found := ix.documents[path]; found {
+		return doc.Fields
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package fulltext
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// snippetContext 摘要中命中位置前后保留的字数
+const snippetContext = 40
+
+// Snippet 命中文字所在的一段摘要
+type Snippet struct {
+	Field string `json:"field"`
+	// HTML 已转义的摘要文本，命中的文字包在 <mark> 中
+	HTML string `json:"html"`
+}
+
+// Snippets 从字段中找出包含搜索语句的摘要，每个字段最多一段，最多返回 limit 段
+func Snippets(fields []Field, query string, limit int) []Snippet {
+	needles := make([][]rune, 0)
+	for _, needle := range highlightNeedles(query) {
+		needles = append(needles, []rune(needle))
+	}
+	snippets := make([]Snippet, 0)
+	if len(needles) == 0 {
+		return snippets
+	}
+	for _, field := range fields {
+		if len(snippets) >= limit {
+			break
+		}
+		if text, ok := highlight(field.Text, needles); ok {
+			snippets = append(snippets, Snippet{Field: field.Name, HTML: text})
+		}
+	}
+	return snippets
+}
+
+// highlight 截取第一个命中位置附近的文字并标出其中所有命中，没有命中时返回 false
+func highlight(text string, needles [][]rune) (string, bool) {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// marked[i] 表示第 i 个字在某个命中之内
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, needle := range needles {
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if !hasPrefixAt(lower, needle, i) {
+				continue
+			}
+			for j := i; j < i+len(needle); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	start := max(0, first-snippetContext)
+	end := min(len(runes), first+snippetContext*2)
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		part := html.EscapeString(collapseSpaces(runes[i:j]))
+		if marked[i] {
+			part = "<mark>" + part + "</mark>"
+		}
+		b.WriteString(part)
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// collapseSpaces 把连续的空白（包括换行）压成一个空格，换行在摘要中没有意义
+func collapseSpaces(runes []rune) string {
+	var b strings.Builder
+	space := false
+	for _, r := range runes {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteRune(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteRune(' ')
+	}
+	return b.String()
+}
+
+// hasPrefixAt 判断 text 从 i 开始是否为 needle
+func hasPrefixAt(text, needle []rune, i int) bool {
+	for j, r := range needle {
+		if text[i+j] != r {
+			return false
+		}
+	}
+	return true
+}
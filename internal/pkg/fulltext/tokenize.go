@@ -0,0 +1,111 @@
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isCJK 判断字符是否属于不以空格分词的中日韩文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// isWordRune 判断字符是否属于以空格分词的单词
+func isWordRune(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !isCJK(r)
+}
+
+// segment 文本中连续的一段单词或中日韩文字，已转为小写
+type segment struct {
+	text string
+	cjk  bool
+}
+
+// segments 把文本切分为单词和中日韩文字段，其余字符作为分隔符
+func segments(text string) []segment {
+	result := make([]segment, 0)
+	var current []rune
+	currentCJK := false
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, segment{text: string(current), cjk: currentCJK})
+			current = current[:0]
+		}
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			if !currentCJK {
+				flush()
+			}
+			currentCJK = true
+			current = append(current, r)
+		case isWordRune(r):
+			if currentCJK {
+				flush()
+			}
+			currentCJK = false
+			current = append(current, unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return result
+}
+
+// QueryTerms 把搜索语句切分为词项
+//
+// 单词整体作为一个词项；中日韩文字没有分隔，按相邻两个字切分（二元组），只有一个字时取单字。
+func QueryTerms(query string) []string {
+	terms := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, seg := range segments(query) {
+		for _, term := range segmentTerms(seg, false) {
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
+
+// indexTerms 把文本切分为索引的词项，中日韩文字额外索引单字，以便搜索单个字
+func indexTerms(text string) []string {
+	terms := make([]string, 0)
+	for _, seg := range segments(text) {
+		terms = append(terms, segmentTerms(seg, true)...)
+	}
+	return terms
+}
+
+// segmentTerms 切分一段文本，unigrams 为 true 时中日韩文字同时输出单字
+func segmentTerms(seg segment, unigrams bool) []string {
+	if !seg.cjk {
+		return []string{seg.text}
+	}
+	runes := []rune(seg.text)
+	if len(runes) == 1 {
+		return []string{seg.text}
+	}
+	terms := make([]string, 0, len(runes)*2)
+	for i := range runes {
+		if unigrams {
+			terms = append(terms, string(runes[i]))
+		}
+		if i+1 < len(runes) {
+			terms = append(terms, string(runes[i:i+2]))
+		}
+	}
+	return terms
+}
+
+// highlightNeedles 搜索语句中要在摘要里高亮的片段：每个单词和每段连续的中日韩文字
+func highlightNeedles(query string) []string {
+	needles := make([]string, 0)
+	for _, seg := range segments(query) {
+		needles = append(needles, strings.ToLower(seg.text))
+	}
+	return needles
+}
@@ -0,0 +1,97 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+
+	_ "golang.org/x/image/webp"
+)
+
+// 图片格式名称，与 image.Decode 返回的格式一致
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+	FormatGIF  = "gif"
+	FormatWebP = "webp"
+)
+
+// Decode 解码 PNG、JPEG、GIF 或 WebP 图片，返回图像和格式名称
+func Decode(data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("无法解码图片: %w", err)
+	}
+	return img, format, nil
+}
+
+// DecodeFile 从文件解码图片
+func DecodeFile(path string) (image.Image, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return Decode(data)
+}
+
+// IsPNG 判断数据是否以 PNG 签名开头
+func IsPNG(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n"))
+}
+
+// ToPNG 将图片转换为 PNG 编码，已是 PNG 时原样返回
+func ToPNG(data []byte) ([]byte, error) {
+	if IsPNG(data) {
+		return data, nil
+	}
+	img, _, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("编码 PNG 失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Extension 根据内容推断图片扩展名（不含点）
+func Extension(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return "png"
+	case "image/jpeg":
+		return "jpg"
+	case "image/gif":
+		return "gif"
+	case "image/webp":
+		return "webp"
+	default:
+		return ""
+	}
+}
+
+// SamePixels 判断两张图片的尺寸和每个像素是否完全相同，与编码方式和元数据无关
+func SamePixels(a, b image.Image) bool {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return false
+	}
+	return bytes.Equal(toNRGBA(a).Pix, toNRGBA(b).Pix)
+}
+
+// toNRGBA 将图片转换为从原点开始的 NRGBA 图像
+func toNRGBA(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	if nrgba, ok := img.(*image.NRGBA); ok && bounds.Min == (image.Point{}) && nrgba.Stride == 4*bounds.Dx() {
+		return nrgba
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(out, out.Bounds(), img, bounds.Min, draw.Src)
+	return out
+}
@@ -0,0 +1,73 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+	"strconv"
+)
+
+const (
+	// dHashWidth 差异哈希缩略图的宽度，比高度多一列用于比较相邻像素
+	dHashWidth  = 9
+	dHashHeight = 8
+)
+
+// DHash 计算图片的差异哈希（dHash）
+//
+// 图片缩小为 9×8 的灰度图后，逐行比较相邻像素的明暗得到 64 位哈希。
+// 重新编码、缩放和轻微裁剪只会改变少数几位，可用 HashDistance 比较。
+func DHash(img image.Image) uint64 {
+	gray := shrinkGray(toNRGBA(img), dHashWidth, dHashHeight)
+	var hash uint64
+	for y := 0; y < dHashHeight; y++ {
+		for x := 0; x < dHashWidth-1; x++ {
+			hash <<= 1
+			if gray[y*dHashWidth+x] < gray[y*dHashWidth+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HashDistance 两个感知哈希之间不同的位数，越小越相似
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// FormatHash 将感知哈希格式化为 16 位十六进制字符串
+func FormatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// ParseHash 解析 FormatHash 生成的字符串
+func ParseHash(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
+
+// shrinkGray 按区域平均将图片缩小为 width×height 的灰度值，透明部分视为白色
+func shrinkGray(img *image.NRGBA, width, height int) []float64 {
+	bounds := img.Bounds()
+	sums := make([]float64, width*height)
+	counts := make([]float64, width*height)
+	for y := 0; y < bounds.Dy(); y++ {
+		cellY := y * height / bounds.Dy()
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < bounds.Dx(); x++ {
+			cellX := x * width / bounds.Dx()
+			p := row[x*4 : x*4+4]
+			luma := 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+			alpha := float64(p[3]) / 255
+			cell := cellY*width + cellX
+			sums[cell] += luma*alpha + 255*(1-alpha)
+			counts[cell]++
+		}
+	}
+	for i := range sums {
+		if counts[i] > 0 {
+			sums[i] /= counts[i]
+		}
+	}
+	return sums
+}
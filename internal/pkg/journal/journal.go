@@ -0,0 +1,401 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"card-manager/internal/pkg/trash"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxOperations 日志中保留的操作数，超出时丢弃最早的记录
+const maxOperations = 500
+
+// 操作步骤的类型
+const (
+	// StepMove 文件或文件夹从 From 移动到 To
+	StepMove = "move"
+	// StepCreate 新建了文件 Path
+	StepCreate = "create"
+	// StepTrash Path 被移入回收站条目 TrashID
+	StepTrash = "trash"
+	// StepOverwrite Path 被覆盖，旧内容备份在回收站条目 TrashID
+	StepOverwrite = "overwrite"
+	// StepMkdir 新建了文件夹 Path
+	StepMkdir = "mkdir"
+)
+
+var (
+	// ErrNotFound 日志中没有指定的操作
+	ErrNotFound = errors.New("操作记录不存在")
+	// ErrAlreadyUndone 操作已经撤销过
+	ErrAlreadyUndone = errors.New("该操作已经撤销过")
+)
+
+// UndoError 操作因文件已发生变化而无法撤销
+type UndoError struct {
+	Operation *Operation
+	Path      string
+	Reason    string
+}
+
+func (e *UndoError) Error() string {
+	return fmt.Sprintf("无法撤销「%s」: %s（%s）", e.Operation.Description, e.Reason, e.Path)
+}
+
+// Step 操作中的一个文件系统变更
+//
+// Fingerprint 记录变更完成后目标的大小和修改时间，撤销前据此判断文件是否又被改动过。
+type Step struct {
+	Kind        string `json:"kind"`
+	Path        string `json:"path,omitempty"`
+	From        string `json:"from,omitempty"`
+	To          string `json:"to,omitempty"`
+	TrashID     string `json:"trashId,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// Operation 一次修改文件的操作
+type Operation struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Description string     `json:"description"`
+	Time        time.Time  `json:"time"`
+	Steps       []Step     `json:"steps"`
+	UndoneAt    *time.Time `json:"undoneAt,omitempty"`
+}
+
+// Journal 操作日志，以 JSON Lines 格式保存
+type Journal struct {
+	path  string
+	trash *trash.Bin
+	mutex sync.Mutex
+}
+
+// New 创建保存在 path 的操作日志，撤销时借助回收站恢复和移走文件
+func New(path string, trashBin *trash.Bin) *Journal {
+	return &Journal{path: path, trash: trashBin}
+}
+
+// Moved 记录移动步骤，须在移动完成后调用
+func Moved(from, to string) Step {
+	return Step{Kind: StepMove, From: from, To: to, Fingerprint: fingerprint(to)}
+}
+
+// Created 记录新建文件步骤，须在写入完成后调用
+func Created(path string) Step {
+	return Step{Kind: StepCreate, Path: path, Fingerprint: fingerprint(path)}
+}
+
+// Trashed 记录移入回收站步骤
+func Trashed(path, trashID string) Step {
+	return Step{Kind: StepTrash, Path: path, TrashID: trashID}
+}
+
+// Overwritten 记录覆盖文件步骤，须在写入完成后调用
+func Overwritten(path, backupID string) Step {
+	return Step{Kind: StepOverwrite, Path: path, TrashID: backupID, Fingerprint: fingerprint(path)}
+}
+
+// MadeDir 记录新建文件夹步骤
+func MadeDir(path string) Step {
+	return Step{Kind: StepMkdir, Path: path}
+}
+
+// Record 追加一条操作记录，写入失败只记录日志，不影响已完成的操作
+//
+// 没有类型的步骤表示实际上没有改动，会被忽略；没有任何步骤时不记录。
+func (j *Journal) Record(opType, description string, steps ...Step) {
+	kept := make([]Step, 0, len(steps))
+	for _, step := range steps {
+		if step.Kind != "" {
+			kept = append(kept, step)
+		}
+	}
+	if len(kept) == 0 {
+		return
+	}
+	steps = kept
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	id, err := newID()
+	if err != nil {
+		slog.Warn("写入操作日志失败", "error", err)
+		return
+	}
+	ops, err := j.load()
+	if err != nil {
+		slog.Warn("读取操作日志失败，将重新开始记录", "error", err)
+		ops = nil
+	}
+	ops = append(ops, Operation{
+		ID:          id,
+		Type:        opType,
+		Description: description,
+		Time:        time.Now(),
+		Steps:       steps,
+	})
+	if len(ops) > maxOperations {
+		ops = ops[len(ops)-maxOperations:]
+	}
+	if err := j.save(ops); err != nil {
+		slog.Warn("写入操作日志失败", "error", err)
+	}
+}
+
+// List 返回最近的 limit 条操作，最新的在前，limit 不大于 0 时返回全部
+func (j *Journal) List(limit int) ([]Operation, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	ops, err := j.load()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Operation, 0, len(ops))
+	for i := len(ops) - 1; i >= 0; i-- {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		result = append(result, ops[i])
+	}
+	return result, nil
+}
+
+// Undo 撤销指定的操作
+func (j *Journal) Undo(id string) (*Operation, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	ops, err := j.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ops {
+		if ops[i].ID != id {
+			continue
+		}
+		if ops[i].UndoneAt != nil {
+			return &ops[i], ErrAlreadyUndone
+		}
+		if err := j.undo(&ops[i]); err != nil {
+			return &ops[i], err
+		}
+		return &ops[i], j.save(ops)
+	}
+	return nil, ErrNotFound
+}
+
+// UndoLast 从最新的操作开始依次撤销 n 个尚未撤销的操作
+//
+// 遇到无法撤销的操作时停止，返回已撤销的操作和该错误。
+func (j *Journal) UndoLast(n int) ([]Operation, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	ops, err := j.load()
+	if err != nil {
+		return nil, err
+	}
+	undone := make([]Operation, 0, n)
+	for i := len(ops) - 1; i >= 0 && len(undone) < n; i-- {
+		if ops[i].UndoneAt != nil {
+			continue
+		}
+		if err = j.undo(&ops[i]); err != nil {
+			break
+		}
+		undone = append(undone, ops[i])
+	}
+	if saveErr := j.save(ops); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return undone, err
+}
+
+// undo 先检查所有步骤都能撤销，再按相反的顺序执行
+func (j *Journal) undo(op *Operation) error {
+	for _, step := range op.Steps {
+		if err := j.check(op, step); err != nil {
+			return err
+		}
+	}
+	for i := len(op.Steps) - 1; i >= 0; i-- {
+		if err := j.revert(op.Steps[i]); err != nil {
+			return fmt.Errorf("撤销「%s」时出错，部分文件可能已恢复: %w", op.Description, err)
+		}
+	}
+	now := time.Now()
+	op.UndoneAt = &now
+	return nil
+}
+
+// check 判断步骤能否撤销
+func (j *Journal) check(op *Operation, step Step) error {
+	fail := func(path, reason string) error {
+		return &UndoError{Operation: op, Path: path, Reason: reason}
+	}
+	switch step.Kind {
+	case StepMove:
+		if !exists(step.To) {
+			return fail(step.To, "文件已不存在")
+		}
+		if fingerprint(step.To) != step.Fingerprint {
+			return fail(step.To, "文件在操作后已被修改")
+		}
+		if exists(step.From) {
+			return fail(step.From, "原位置已被占用")
+		}
+	case StepCreate, StepOverwrite:
+		if !exists(step.Path) {
+			return fail(step.Path, "文件已不存在")
+		}
+		if fingerprint(step.Path) != step.Fingerprint {
+			return fail(step.Path, "文件在操作后已被修改")
+		}
+		if step.Kind == StepOverwrite {
+			if _, err := j.trash.Get(step.TrashID); err != nil {
+				return fail(step.Path, "覆盖前的备份已从回收站清除")
+			}
+		}
+	case StepTrash:
+		if _, err := j.trash.Get(step.TrashID); err != nil {
+			return fail(step.Path, "文件已从回收站清除或恢复")
+		}
+		if exists(step.Path) {
+			return fail(step.Path, "原位置已被占用")
+		}
+	case StepMkdir:
+	default:
+		return fail(step.Path, "未知的操作步骤 "+step.Kind)
+	}
+	return nil
+}
+
+// revert 撤销单个步骤，撤销时移走的文件都进入回收站而不是直接删除
+func (j *Journal) revert(step Step) error {
+	switch step.Kind {
+	case StepMove:
+		if err := os.MkdirAll(filepath.Dir(step.From), 0755); err != nil {
+			return err
+		}
+		return os.Rename(step.To, step.From)
+	case StepCreate:
+		_, err := j.trash.Trash(step.Path, trash.KindUndo)
+		return err
+	case StepOverwrite:
+		if _, err := j.trash.Trash(step.Path, trash.KindUndo); err != nil {
+			return err
+		}
+		_, err := j.trash.Restore(step.TrashID)
+		return err
+	case StepTrash:
+		_, err := j.trash.Restore(step.TrashID)
+		return err
+	case StepMkdir:
+		// 文件夹中还有其他文件时保留
+		if entries, err := os.ReadDir(step.Path); err == nil && len(entries) == 0 {
+			return os.Remove(step.Path)
+		}
+	}
+	return nil
+}
+
+// load 读取所有操作，文件不存在时返回空列表
+func (j *Journal) load() ([]Operation, error) {
+	data, err := os.ReadFile(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ops []Operation
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var op Operation
+		if err := json.Unmarshal(line, &op); err != nil {
+			slog.Warn("跳过损坏的操作记录", "error", err)
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return ops, scanner.Err()
+}
+
+// save 写入所有操作，先写临时文件再重命名
+func (j *Journal) save(ops []Operation) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	for _, op := range ops {
+		if err := encoder.Encode(op); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := j.path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, j.path)
+}
+
+// fingerprint 文件的大小和修改时间；文件夹为其中所有文件的汇总，忽略以点开头的附属文件
+func fingerprint(path string) string {
+	stats, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	if !stats.IsDir() {
+		return fmt.Sprintf("%d-%d", stats.Size(), stats.ModTime().UnixNano())
+	}
+
+	var lines []string
+	filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		rel, _ := filepath.Rel(path, p)
+		lines = append(lines, fmt.Sprintf("%s:%d-%d", filepath.ToSlash(rel), info.Size(), info.ModTime().UnixNano()))
+		return nil
+	})
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// newID 生成按时间排序的操作 ID
+func newID() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix), nil
+}
@@ -0,0 +1,106 @@
+// Package jsonfile 保存在角色库元数据目录中的小型 JSON 文件
+//
+// 标签登记表、智能收藏夹等文件读取后缓存在内存中，文件在外部被改动
+// （如撤销操作恢复了旧文件）时重新读取，写入时先写临时文件再重命名。
+package jsonfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store 以 JSON 格式保存的 T
+type Store[T any] struct {
+	path  string
+	clone func(v *T) *T
+	mutex sync.Mutex
+	value *T
+	stamp string
+}
+
+// New 创建保存在 path 的文件，clone 复制内容，避免调用方修改缓存的内容
+func New[T any](path string, clone func(v *T) *T) *Store[T] {
+	return &Store[T]{path: path, clone: clone}
+}
+
+// Path 文件的路径
+func (s *Store[T]) Path() string {
+	return s.path
+}
+
+// Load 返回文件内容的副本，文件不存在时为零值
+func (s *Store[T]) Load() (*T, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s.clone(s.value), nil
+}
+
+// Update 读取、修改并保存文件，modify 返回错误时不写入
+func (s *Store[T]) Update(modify func(v *T) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	value := s.clone(s.value)
+	if err := modify(value); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return err
+	}
+	s.value = value
+	s.stamp = Stamp(s.path)
+	return nil
+}
+
+// load 第一次使用或文件变化后读取文件，调用方需持有锁
+func (s *Store[T]) load() error {
+	stamp := Stamp(s.path)
+	if s.value != nil && stamp == s.stamp {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		s.value = new(T)
+		s.stamp = stamp
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	value := new(T)
+	if err := json.Unmarshal(data, value); err != nil {
+		return err
+	}
+	s.value = value
+	s.stamp = stamp
+	return nil
+}
+
+// Stamp 文件或文件夹的大小和修改时间，不存在时为空串
+func Stamp(path string) string {
+	stats, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", stats.Size(), stats.ModTime().UnixNano())
+}
@@ -29,6 +29,11 @@ func (s *Service) buildNikoPath() string {
 	return filepath.Join(s.tavernPublicPath, "niko")
 }
 
+// NikoPath 本地化资源所在的目录，每个已本地化的角色在其中有一个文件夹
+func (s *Service) NikoPath() string {
+	return s.buildNikoPath()
+}
+
 // 调用 localizer.Run 来判断角色卡是否需要本地化
 func (s *Service) CheckLocalizationNeeded(cardPath string) (bool, error) {
 	opts := localizer.Options{
@@ -47,16 +52,22 @@ func (s *Service) CheckLocalizationNeeded(cardPath string) (bool, error) {
 
 // 检查角色卡是否已经被本地化
 func (s *Service) IsLocalized(characterName string) (bool, error) {
+	folderName, err := s.LocalizedFolder(characterName)
+	return folderName != "", err
+}
+
+// 查找角色在niko目录中的本地化资源文件夹名，未本地化时返回空串
+func (s *Service) LocalizedFolder(characterName string) (string, error) {
 	nikoBasePath := s.buildNikoPath()
 	if nikoBasePath == "" {
-		return false, fmt.Errorf("无法构建Niko路径：酒馆公共目录未配置")
+		return "", fmt.Errorf("无法构建Niko路径：酒馆公共目录未配置")
 	}
 
 	// 检查原始名称
 	nikoPath := filepath.Join(nikoBasePath, characterName)
 	info, err := os.Stat(nikoPath)
 	if err == nil && info.IsDir() {
-		return true, nil
+		return characterName, nil
 	}
 
 	// 使用正则表达式移除所有非字母数字字符
@@ -65,14 +76,14 @@ func (s *Service) IsLocalized(characterName string) (bool, error) {
 	nikoPathSanitized := filepath.Join(nikoBasePath, sanitizedName)
 	info, err = os.Stat(nikoPathSanitized)
 	if err == nil && info.IsDir() {
-		return true, nil
+		return sanitizedName, nil
 	}
 
 	if os.IsNotExist(err) {
-		return false, nil
+		return "", nil
 	}
 
-	return false, err
+	return "", err
 }
 
 // 调用 localizer.Run 来执行本地化操作
@@ -0,0 +1,112 @@
+package png
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Signature PNG 文件签名
+const Signature = "\x89PNG\r\n\x1a\n"
+
+// 预定义错误
+var (
+	ErrNotPNG          = errors.New("不是有效的 PNG 文件")
+	ErrIENDNotFound    = errors.New("未在原始图像中找到 IEND 块")
+	ErrNoCharacterData = errors.New("PNG 中没有角色卡数据")
+	ErrChunkTooLarge   = errors.New("PNG 块长度超出规范上限")
+)
+
+// maxChunkLength PNG 规范允许的最大块长度（2^31-1）
+const maxChunkLength = 1<<31 - 1
+
+// chunk 代表一个 PNG 块
+type chunk struct {
+	Length uint32
+	Type   string
+	Data   []byte
+	CRC    uint32
+}
+
+// readSignature 读取并校验 PNG 签名
+func readSignature(reader io.Reader) error {
+	header := make([]byte, len(Signature))
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return fmt.Errorf("读取 PNG 签名失败: %w", err)
+	}
+	if string(header) != Signature {
+		return ErrNotPNG
+	}
+	return nil
+}
+
+// readChunk 从 reader 中读取单个 PNG 块
+func readChunk(reader io.Reader) (*chunk, error) {
+	var length uint32
+	if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	chunkType := make([]byte, 4)
+	if _, err := io.ReadFull(reader, chunkType); err != nil {
+		return nil, fmt.Errorf("读取块类型和数据失败: %w", err)
+	}
+	data, err := readChunkData(reader, length)
+	if err != nil {
+		return nil, fmt.Errorf("读取块类型和数据失败: %w", err)
+	}
+
+	var crc uint32
+	if err := binary.Read(reader, binary.BigEndian, &crc); err != nil {
+		return nil, fmt.Errorf("读取块 CRC 失败: %w", err)
+	}
+
+	return &chunk{
+		Length: length,
+		Type:   string(chunkType),
+		Data:   data,
+		CRC:    crc,
+	}, nil
+}
+
+// readChunkData 读取长度为 length 的块数据
+//
+// 长度来自文件本身，不可信：超出规范上限的直接拒绝，其余按实际读到的数据
+// 逐步扩容，损坏的长度字段不会导致一次性分配巨大的缓冲区。
+func readChunkData(reader io.Reader, length uint32) ([]byte, error) {
+	if length > maxChunkLength {
+		return nil, fmt.Errorf("%w: %d", ErrChunkTooLarge, length)
+	}
+	var buf bytes.Buffer
+	n, err := buf.ReadFrom(io.LimitReader(reader, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+	if n < int64(length) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), nil
+}
+
+// writeChunk 将单个 PNG 块写入 writer，CRC 按内容重新计算
+func writeChunk(writer io.Writer, ch *chunk) error {
+	if err := binary.Write(writer, binary.BigEndian, uint32(len(ch.Data))); err != nil {
+		return err
+	}
+
+	typeBytes := []byte(ch.Type)
+	if _, err := writer.Write(typeBytes); err != nil {
+		return err
+	}
+	if _, err := writer.Write(ch.Data); err != nil {
+		return err
+	}
+
+	crc := crc32.NewIEEE()
+	crc.Write(typeBytes)
+	crc.Write(ch.Data)
+	return binary.Write(writer, binary.BigEndian, crc.Sum32())
+}
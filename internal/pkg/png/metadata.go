@@ -0,0 +1,196 @@
+package png
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CharacterData 从文本块中选出角色卡数据，'ccv3' 优先于 'chara'
+//
+// 返回 Base64 编码的数据和其所在的关键字。
+func CharacterData(texts []TextChunk) (string, string, error) {
+	var charaData string
+	for _, tc := range texts {
+		switch tc.Keyword {
+		case KeywordCCv3:
+			if tc.Text != "" {
+				return tc.Text, KeywordCCv3, nil
+			}
+		case KeywordChara:
+			if charaData == "" {
+				charaData = tc.Text
+			}
+		}
+	}
+	if charaData != "" {
+		return charaData, KeywordChara, nil
+	}
+	return "", "", ErrNoCharacterData
+}
+
+// GetCharacterData 读取 PNG 文件中的角色卡数据，'ccv3' 优先于 'chara'
+func GetCharacterData(filePath string) (string, error) {
+	texts, err := ReadTextChunks(filePath)
+	if err != nil {
+		return "", err
+	}
+	data, _, err := CharacterData(texts)
+	return data, err
+}
+
+// WriteTextChunks 复制 PNG 并写入文本块
+//
+// 原图中关键字相同的文本块（无论 tEXt、zTXt 还是 iTXt）会被替换，
+// 新的文本块写在 IEND 之前。
+func WriteTextChunks(reader io.Reader, writer io.Writer, texts []TextChunk) error {
+	return rewriteTextChunks(reader, writer, texts, nil)
+}
+
+// IsCharacterKeyword 判断文本块关键字是否属于角色卡数据（chara、ccv3 或内嵌资源）
+func IsCharacterKeyword(keyword string) bool {
+	return keyword == KeywordChara || keyword == KeywordCCv3 || strings.HasPrefix(keyword, KeywordAssetPrefix)
+}
+
+// CharacterTextChunks 从文本块中挑出角色卡数据块和内嵌资源块
+func CharacterTextChunks(texts []TextChunk) []TextChunk {
+	result := make([]TextChunk, 0, 2)
+	for _, tc := range texts {
+		if IsCharacterKeyword(tc.Keyword) {
+			result = append(result, tc)
+		}
+	}
+	return result
+}
+
+// StripCharacterData 去掉图片中的角色卡数据块和内嵌资源块，其余块保持不变
+func StripCharacterData(imageData []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	err := rewriteTextChunks(bytes.NewReader(imageData), &buf, nil, IsCharacterKeyword)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteImageWithTextChunks 将内存中的 PNG 图片写入 outputPath 并附加文本块
+func WriteImageWithTextChunks(imageData []byte, outputPath string, texts ...TextChunk) error {
+	return writeFileAtomic(outputPath, func(w io.Writer) error {
+		return WriteTextChunks(bytes.NewReader(imageData), w, texts)
+	})
+}
+
+// rewriteTextChunks 复制 PNG，去掉被替换或 drop 命中的文本块，并在 IEND 前写入新文本块
+func rewriteTextChunks(reader io.Reader, writer io.Writer, texts []TextChunk, drop func(keyword string) bool) error {
+	if err := readSignature(reader); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(writer, Signature); err != nil {
+		return err
+	}
+
+	replaced := make(map[string]bool, len(texts))
+	newChunks := make([]*chunk, 0, len(texts))
+	for _, tc := range texts {
+		ch, err := encodeTextChunk(tc)
+		if err != nil {
+			return err
+		}
+		replaced[tc.Keyword] = true
+		newChunks = append(newChunks, ch)
+	}
+
+	for {
+		ch, err := readChunk(reader)
+		if err != nil {
+			if err == io.EOF {
+				return ErrIENDNotFound
+			}
+			return err
+		}
+
+		if isTextChunkType(ch.Type) {
+			keyword := textKeyword(ch.Data)
+			if replaced[keyword] || (drop != nil && drop(keyword)) {
+				continue
+			}
+		}
+
+		if ch.Type == "IEND" {
+			for _, newChunk := range newChunks {
+				if err := writeChunk(writer, newChunk); err != nil {
+					return fmt.Errorf("写入 %s 块失败: %w", newChunk.Type, err)
+				}
+			}
+			if err := writeChunk(writer, ch); err != nil {
+				return fmt.Errorf("写入 IEND 块失败: %w", err)
+			}
+			return nil
+		}
+
+		if err := writeChunk(writer, ch); err != nil {
+			return fmt.Errorf("写入块 %s 失败: %w", ch.Type, err)
+		}
+	}
+}
+
+// WriteTextChunksToFile 将原图复制到 outputPath 并写入文本块
+//
+// 先写入同目录下的临时文件再重命名，因此 outputPath 可以与原图相同。
+func WriteTextChunksToFile(originalImagePath, outputPath string, texts ...TextChunk) error {
+	return writeFileAtomic(outputPath, func(w io.Writer) error {
+		// 原图须在重命名前关闭，否则在 Windows 上无法覆盖自身
+		inputFile, err := os.Open(originalImagePath)
+		if err != nil {
+			return err
+		}
+		defer inputFile.Close()
+		return WriteTextChunks(bufio.NewReader(inputFile), w, texts)
+	})
+}
+
+// WriteCharacterData 写入 'chara'（V2）和 'ccv3'（V3）两个角色卡数据块
+func WriteCharacterData(originalImagePath, outputPath, charaV2, charaV3 string) error {
+	return WriteTextChunksToFile(originalImagePath, outputPath,
+		TextChunk{Type: TypeText, Keyword: KeywordChara, Text: charaV2},
+		TextChunk{Type: TypeText, Keyword: KeywordCCv3, Text: charaV3},
+	)
+}
+
+// WriteCharaToPNG 将 'chara' 数据写入新的 PNG 文件
+func WriteCharaToPNG(originalImagePath, outputPath, charaData string) error {
+	return WriteTextChunksToFile(originalImagePath, outputPath,
+		TextChunk{Type: TypeText, Keyword: KeywordChara, Text: charaData},
+	)
+}
+
+// writeFileAtomic 通过临时文件写入目标文件，失败时不会留下半成品
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	buffered := bufio.NewWriter(tmp)
+	if err := write(buffered); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
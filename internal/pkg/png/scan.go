@@ -0,0 +1,130 @@
+package png
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// scanBufferSize 单次扫描时的读缓冲大小
+const scanBufferSize = 64 * 1024
+
+// ScanResult 单次读取 PNG 文件得到的哈希、文本块和完整性问题
+type ScanResult struct {
+	Hash     string
+	Texts    []TextChunk
+	Problems []string
+}
+
+// DecodeTextChunks 从 reader 中读取所有可解码的文本块
+//
+// 只有文本块的数据会读入内存；reader 实现 io.Seeker 时直接跳过其余块
+// （如体积很大的 IDAT），否则读取后丢弃。
+func DecodeTextChunks(reader io.Reader) ([]TextChunk, error) {
+	if err := readSignature(reader); err != nil {
+		return nil, err
+	}
+
+	var texts []TextChunk
+	for {
+		length, chunkType, err := readChunkHeader(reader)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if chunkType == "IEND" {
+			break
+		}
+		if !isTextChunkType(chunkType) {
+			if err := skipBytes(reader, int64(length)+4); err != nil {
+				return nil, fmt.Errorf("跳过块 %s 失败: %w", chunkType, err)
+			}
+			continue
+		}
+
+		data, err := readChunkData(reader, length)
+		if err != nil {
+			return nil, fmt.Errorf("读取块类型和数据失败: %w", err)
+		}
+		if err := skipBytes(reader, 4); err != nil {
+			return nil, fmt.Errorf("读取块 CRC 失败: %w", err)
+		}
+		tc, err := decodeTextChunk(chunkType, data)
+		if err != nil {
+			// 忽略无法解码的文本块
+			continue
+		}
+		texts = append(texts, tc)
+	}
+	return texts, nil
+}
+
+// ReadTextChunks 读取 PNG 文件中的所有文本块（tEXt、zTXt、iTXt），跳过图像数据
+func ReadTextChunks(filePath string) ([]TextChunk, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return DecodeTextChunks(file)
+}
+
+// Scan 一次读取 reader 的全部内容，同时计算 SHA-256、校验完整性并提取文本块
+//
+// IEND 之后的多余数据也计入哈希，与直接对整个文件求哈希的结果一致。
+func Scan(reader io.Reader) (*ScanResult, error) {
+	hash := sha256.New()
+	tee := io.TeeReader(reader, hash)
+
+	texts, problems := Verify(tee)
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return nil, err
+	}
+	return &ScanResult{
+		Hash:     hex.EncodeToString(hash.Sum(nil)),
+		Texts:    texts,
+		Problems: problems,
+	}, nil
+}
+
+// ScanFile 单次读取 PNG 文件，返回哈希、文本块和完整性问题
+//
+// 哈希覆盖整个文件，因此图像数据也会全部读取；只需要文本块时使用 ReadTextChunks。
+func ScanFile(filePath string) (*ScanResult, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Scan(bufio.NewReaderSize(file, scanBufferSize))
+}
+
+// readChunkHeader 读取块的长度和类型
+func readChunkHeader(reader io.Reader) (uint32, string, error) {
+	var head [8]byte
+	if _, err := io.ReadFull(reader, head[:]); err != nil {
+		return 0, "", err
+	}
+	return binary.BigEndian.Uint32(head[:4]), string(head[4:]), nil
+}
+
+// skipBytes 跳过 reader 中的 n 个字节，可定位时直接 Seek
+func skipBytes(reader io.Reader, n int64) error {
+	if seeker, ok := reader.(io.Seeker); ok {
+		_, err := seeker.Seek(n, io.SeekCurrent)
+		return err
+	}
+	skipped, err := io.CopyN(io.Discard, reader, n)
+	if err == io.EOF && skipped < n {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,217 @@
+package png
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// 文本块类型
+const (
+	TypeText           = "tEXt"
+	TypeCompressedText = "zTXt"
+	TypeInternational  = "iTXt"
+)
+
+// 角色卡使用的关键字
+const (
+	KeywordChara = "chara"
+	KeywordCCv3  = "ccv3"
+	// KeywordAssetPrefix V3 规范中 PNG 内嵌资源块的关键字前缀，完整关键字为前缀加资源路径
+	KeywordAssetPrefix = "chara-ext-asset_:"
+)
+
+// TextChunk 解码后的文本块，Text 统一为 UTF-8
+type TextChunk struct {
+	// Type 块类型（tEXt/zTXt/iTXt），写入时为空表示自动选择
+	Type    string `json:"type"`
+	Keyword string `json:"keyword"`
+	Text    string `json:"text"`
+	// Compressed 仅对 iTXt 有意义，表示文本是否经过 zlib 压缩
+	Compressed        bool   `json:"compressed,omitempty"`
+	LanguageTag       string `json:"languageTag,omitempty"`
+	TranslatedKeyword string `json:"translatedKeyword,omitempty"`
+}
+
+// isTextChunkType 判断块类型是否为文本块
+func isTextChunkType(chunkType string) bool {
+	return chunkType == TypeText || chunkType == TypeCompressedText || chunkType == TypeInternational
+}
+
+// textKeyword 在不解压的情况下读取文本块的关键字
+func textKeyword(data []byte) string {
+	keyword, _, _ := bytes.Cut(data, []byte{0})
+	return latin1ToUTF8(keyword)
+}
+
+// decodeTextChunk 解码 tEXt、zTXt 或 iTXt 块
+func decodeTextChunk(chunkType string, data []byte) (TextChunk, error) {
+	keyword, rest, found := bytes.Cut(data, []byte{0})
+	if !found {
+		return TextChunk{}, fmt.Errorf("%s 块缺少关键字分隔符", chunkType)
+	}
+	tc := TextChunk{Type: chunkType, Keyword: latin1ToUTF8(keyword)}
+
+	switch chunkType {
+	case TypeText:
+		tc.Text = latin1ToUTF8(rest)
+	case TypeCompressedText:
+		if len(rest) < 1 {
+			return TextChunk{}, errors.New("zTXt 块缺少压缩方式")
+		}
+		if rest[0] != 0 {
+			return TextChunk{}, fmt.Errorf("zTXt 块使用了未知的压缩方式 %d", rest[0])
+		}
+		text, err := inflate(rest[1:])
+		if err != nil {
+			return TextChunk{}, fmt.Errorf("解压 zTXt 块失败: %w", err)
+		}
+		tc.Text = latin1ToUTF8(text)
+	case TypeInternational:
+		if len(rest) < 2 {
+			return TextChunk{}, errors.New("iTXt 块缺少压缩标志")
+		}
+		compressed, method := rest[0] == 1, rest[1]
+		language, rest, ok := bytes.Cut(rest[2:], []byte{0})
+		if !ok {
+			return TextChunk{}, errors.New("iTXt 块缺少语言标签")
+		}
+		translated, text, ok := bytes.Cut(rest, []byte{0})
+		if !ok {
+			return TextChunk{}, errors.New("iTXt 块缺少翻译关键字")
+		}
+		if compressed {
+			if method != 0 {
+				return TextChunk{}, fmt.Errorf("iTXt 块使用了未知的压缩方式 %d", method)
+			}
+			inflated, err := inflate(text)
+			if err != nil {
+				return TextChunk{}, fmt.Errorf("解压 iTXt 块失败: %w", err)
+			}
+			text = inflated
+		}
+		tc.Compressed = compressed
+		tc.LanguageTag = string(language)
+		tc.TranslatedKeyword = string(translated)
+		tc.Text = string(text)
+	default:
+		return TextChunk{}, fmt.Errorf("%s 不是文本块", chunkType)
+	}
+	return tc, nil
+}
+
+// encodeTextChunk 将文本块编码为 PNG 块
+//
+// 未指定类型时优先使用 tEXt，文本无法用 Latin-1 表示时改用 iTXt。
+func encodeTextChunk(tc TextChunk) (*chunk, error) {
+	if tc.Keyword == "" || len(tc.Keyword) > 79 {
+		return nil, fmt.Errorf("无效的文本块关键字 %q", tc.Keyword)
+	}
+	keyword, ok := utf8ToLatin1(tc.Keyword)
+	if !ok {
+		return nil, fmt.Errorf("关键字 %q 无法用 Latin-1 表示", tc.Keyword)
+	}
+
+	chunkType := tc.Type
+	latin1Text, isLatin1 := utf8ToLatin1(tc.Text)
+	if chunkType == "" {
+		chunkType = TypeText
+		if !isLatin1 {
+			chunkType = TypeInternational
+		}
+	}
+	if chunkType != TypeInternational && !isLatin1 {
+		return nil, fmt.Errorf("%s 块的文本无法用 Latin-1 表示", chunkType)
+	}
+
+	data := append(keyword, 0)
+	switch chunkType {
+	case TypeText:
+		data = append(data, latin1Text...)
+	case TypeCompressedText:
+		compressed, err := deflate(latin1Text)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, 0)
+		data = append(data, compressed...)
+	case TypeInternational:
+		text := []byte(tc.Text)
+		if tc.Compressed {
+			compressed, err := deflate(text)
+			if err != nil {
+				return nil, err
+			}
+			text = compressed
+			data = append(data, 1, 0)
+		} else {
+			data = append(data, 0, 0)
+		}
+		data = append(data, tc.LanguageTag...)
+		data = append(data, 0)
+		data = append(data, tc.TranslatedKeyword...)
+		data = append(data, 0)
+		data = append(data, text...)
+	default:
+		return nil, fmt.Errorf("%s 不是文本块", chunkType)
+	}
+
+	return &chunk{Type: chunkType, Data: data, Length: uint32(len(data))}, nil
+}
+
+// inflate 解压 zlib 数据
+func inflate(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// deflate 使用 zlib 压缩数据
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := zlib.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// latin1ToUTF8 将 Latin-1 字节转换为 UTF-8 字符串
+func latin1ToUTF8(data []byte) string {
+	ascii := true
+	for _, b := range data {
+		if b >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return string(data)
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// utf8ToLatin1 将 UTF-8 字符串转换为 Latin-1 字节，无法表示时返回 false
+func utf8ToLatin1(s string) ([]byte, bool) {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			return nil, false
+		}
+		out = append(out, byte(r))
+	}
+	return out, true
+}
@@ -0,0 +1,105 @@
+package png
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// 完整性问题，用于在版本信息中标记损坏的文件
+const (
+	ProblemBadSignature = "bad signature"
+	ProblemTruncated    = "truncated"
+	ProblemCRCMismatch  = "CRC mismatch"
+	ProblemMissingIEND  = "missing IEND"
+)
+
+// Verify 校验 PNG 的签名、各块的 CRC 以及 IEND 结束块
+//
+// 与 DecodeTextChunks 不同，遇到问题时不会中止，而是尽量读完并返回发现的问题
+// 以及所有可以解码的文本块（包括 CRC 不符的）。非文本块只计算 CRC，不保留数据。
+func Verify(reader io.Reader) ([]TextChunk, []string) {
+	var texts []TextChunk
+	var problems []string
+	addProblem := func(problem string) {
+		for _, p := range problems {
+			if p == problem {
+				return
+			}
+		}
+		problems = append(problems, problem)
+	}
+
+	header := make([]byte, len(Signature))
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if string(header) == Signature[:len(header)] {
+			return nil, []string{ProblemTruncated}
+		}
+		return nil, []string{ProblemBadSignature}
+	}
+	if string(header) != Signature {
+		return nil, []string{ProblemBadSignature}
+	}
+
+	for {
+		length, chunkType, err := readChunkHeader(reader)
+		if err != nil {
+			if err == io.EOF {
+				addProblem(ProblemMissingIEND)
+			} else {
+				addProblem(ProblemTruncated)
+			}
+			break
+		}
+
+		crc := crc32.NewIEEE()
+		crc.Write([]byte(chunkType))
+		var data []byte
+		var n int64
+		if isTextChunkType(chunkType) {
+			data, _ = io.ReadAll(io.LimitReader(reader, int64(length)))
+			crc.Write(data)
+			n = int64(len(data))
+		} else {
+			n, _ = io.CopyN(crc, reader, int64(length))
+		}
+		if n < int64(length) {
+			addProblem(ProblemTruncated)
+			break
+		}
+
+		var storedCRC uint32
+		if err := binary.Read(reader, binary.BigEndian, &storedCRC); err != nil {
+			addProblem(ProblemTruncated)
+			break
+		}
+		if storedCRC != crc.Sum32() {
+			addProblem(ProblemCRCMismatch)
+		}
+		// CRC 错误只作为问题报告，文本块仍然解码，与读取时忽略 CRC 的行为一致
+		if data != nil {
+			if tc, err := decodeTextChunk(chunkType, data); err == nil {
+				texts = append(texts, tc)
+			}
+		}
+
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return texts, problems
+}
+
+// VerifyFile 校验 PNG 文件的完整性，仅在无法打开文件时返回错误
+func VerifyFile(filePath string) ([]TextChunk, []string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	texts, problems := Verify(bufio.NewReader(file))
+	return texts, problems, nil
+}
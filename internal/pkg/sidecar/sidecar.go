@@ -0,0 +1,214 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileName 角色文件夹中的附属数据文件名，随文件夹一起移动
+const FileName = ".card-manager.json"
+
+// mutex 串行化所有附属文件的读改写，避免并发请求互相覆盖
+var mutex sync.Mutex
+
+// 角色的状态，未设置时为 StatusNew
+const (
+	StatusNew     = "new"
+	StatusTried   = "tried"
+	StatusKeeper  = "keeper"
+	StatusDropped = "dropped"
+)
+
+// Statuses 所有状态，按使用的先后排列
+var Statuses = []string{StatusNew, StatusTried, StatusKeeper, StatusDropped}
+
+// MaxRating 评分的上限，评分为 0 表示未评分
+const MaxRating = 5
+
+// Sidecar 角色文件夹的附属数据
+type Sidecar struct {
+	Versions []VersionNote `json:"versions,omitempty"`
+	// CurrentVersion 手动指定的当前版本，为空时以修改时间最新的版本为准
+	CurrentVersion *VersionKey `json:"currentVersion,omitempty"`
+	// LocalizedName 角色改名前本地化资源在 niko 目录中的文件夹名，改名后仍沿用这些资源
+	LocalizedName string `json:"localizedName,omitempty"`
+	// Tags 用户给角色添加的标签，角色卡内嵌的标签不在其中
+	Tags []string `json:"tags,omitempty"`
+	// Favorite、Rating 和 Status 为用户对角色的收藏、评分和状态，Status 为空时即 StatusNew
+	Favorite bool   `json:"favorite,omitempty"`
+	Rating   int    `json:"rating,omitempty"`
+	Status   string `json:"status,omitempty"`
+}
+
+// VersionNote 单个版本的标签和备注
+//
+// 以文件内容的哈希对应版本，因此版本文件改名后仍能找到；
+// 文件内容变化时退回按文件名对应。
+type VersionNote struct {
+	Hash     string `json:"hash"`
+	FileName string `json:"fileName"`
+	Label    string `json:"label,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// IsSidecar 判断文件名是否为附属数据文件
+func IsSidecar(fileName string) bool {
+	return fileName == FileName
+}
+
+// Load 读取角色文件夹的附属数据，文件不存在时返回空数据
+func Load(folderPath string) (*Sidecar, error) {
+	data, err := os.ReadFile(filepath.Join(folderPath, FileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Sidecar{}, nil
+		}
+		return &Sidecar{}, err
+	}
+
+	var s Sidecar
+	if err := json.Unmarshal(data, &s); err != nil {
+		return &Sidecar{}, err
+	}
+	return &s, nil
+}
+
+// Update 读取、修改并保存角色文件夹的附属数据
+func Update(folderPath string, modify func(s *Sidecar) error) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	s, err := Load(folderPath)
+	if err != nil {
+		return err
+	}
+	if err := modify(s); err != nil {
+		return err
+	}
+	return s.save(folderPath)
+}
+
+// save 保存附属数据，没有任何内容时删除文件
+func (s *Sidecar) save(folderPath string) error {
+	filePath := filepath.Join(folderPath, FileName)
+	if s.isEmpty() {
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
+
+func (s *Sidecar) isEmpty() bool {
+	return len(s.Versions) == 0 && s.CurrentVersion == nil && s.LocalizedName == "" && len(s.Tags) == 0 &&
+		!s.Favorite && s.Rating == 0 && (s.Status == "" || s.Status == StatusNew)
+}
+
+// VersionKey 用于对应版本记录的哈希和文件名
+type VersionKey struct {
+	Hash     string `json:"hash"`
+	FileName string `json:"fileName"`
+}
+
+// MatchVersions 为文件夹中的一组版本查找标签和备注，结果与 keys 一一对应
+//
+// 先按哈希对应，剩下的记录再按文件名对应，每条记录最多对应一个版本，
+// 以免改名后原文件名被新文件占用时同一备注出现两次。
+func (s *Sidecar) MatchVersions(keys []VersionKey) []*VersionNote {
+	result := make([]*VersionNote, len(keys))
+	used := make([]bool, len(s.Versions))
+	for k, key := range keys {
+		if key.Hash == "" {
+			continue
+		}
+		for i := range s.Versions {
+			if !used[i] && s.Versions[i].Hash == key.Hash {
+				result[k] = &s.Versions[i]
+				used[i] = true
+				break
+			}
+		}
+	}
+	for k, key := range keys {
+		if result[k] != nil {
+			continue
+		}
+		for i := range s.Versions {
+			if !used[i] && s.Versions[i].FileName == key.FileName {
+				result[k] = &s.Versions[i]
+				used[i] = true
+				break
+			}
+		}
+	}
+	return result
+}
+
+// SetVersionNote 设置版本的标签和备注，两者都为空时删除记录
+func (s *Sidecar) SetVersionNote(hash, fileName, label, comment string) {
+	i := s.findVersion(hash, fileName)
+	if label == "" && comment == "" {
+		if i >= 0 {
+			s.Versions = append(s.Versions[:i], s.Versions[i+1:]...)
+		}
+		return
+	}
+
+	note := VersionNote{Hash: hash, FileName: fileName, Label: label, Comment: comment}
+	if i >= 0 {
+		s.Versions[i] = note
+	} else {
+		s.Versions = append(s.Versions, note)
+	}
+}
+
+// findVersion 返回版本记录的下标，找不到时返回 -1
+func (s *Sidecar) findVersion(hash, fileName string) int {
+	if hash != "" {
+		for i, note := range s.Versions {
+			if note.Hash == hash {
+				return i
+			}
+		}
+	}
+	for i, note := range s.Versions {
+		if note.FileName == fileName {
+			return i
+		}
+	}
+	return -1
+}
+
+// CurrentIndex 返回指定为当前版本的下标，未指定或该版本已不存在时返回 -1
+//
+// 与版本备注一样先按哈希、再按文件名对应。
+func (s *Sidecar) CurrentIndex(keys []VersionKey) int {
+	if s.CurrentVersion == nil {
+		return -1
+	}
+	if s.CurrentVersion.Hash != "" {
+		for i, key := range keys {
+			if key.Hash == s.CurrentVersion.Hash {
+				return i
+			}
+		}
+	}
+	for i, key := range keys {
+		if key.FileName == s.CurrentVersion.FileName {
+			return i
+		}
+	}
+	return -1
+}
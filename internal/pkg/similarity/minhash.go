@@ -0,0 +1,130 @@
+package similarity
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// shingleSize 按字符切分的片段长度，按字符而非单词切分以兼容中日韩文本
+	shingleSize = 5
+	// signatureSize 签名的分桶数，越大估计越准
+	signatureSize = 128
+	// bandRows 局部敏感哈希中每个分段的行数
+	bandRows = 4
+	// minShingles 片段太少的文本相似度没有意义
+	minShingles = 20
+
+	emptyBin = math.MaxUint64
+)
+
+// Signature 文本的 MinHash 签名，用于估计两段文本片段集合的 Jaccard 相似度
+//
+// 使用单次哈希分桶（one permutation hashing）：每个片段只哈希一次，
+// 按哈希值对桶数取模分入各桶（mix 已将高位打散到低位），桶内保留最小值。
+type Signature [signatureSize]uint64
+
+// Pair 相似度达到阈值的一对文本
+type Pair struct {
+	I, J       int
+	Similarity float64
+}
+
+// TextSignature 计算文本的签名，文本过短时返回 false
+func TextSignature(text string) (Signature, bool) {
+	var sig Signature
+	for i := range sig {
+		sig[i] = emptyBin
+	}
+
+	runes := []rune(Normalize(text))
+	if len(runes)-shingleSize+1 < minShingles {
+		return sig, false
+	}
+
+	buf := make([]byte, 0, shingleSize*utf8.UTFMax)
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		buf = buf[:0]
+		for _, r := range runes[i : i+shingleSize] {
+			buf = utf8.AppendRune(buf, r)
+		}
+		h := fnv.New64a()
+		h.Write(buf)
+		value := mix(h.Sum64())
+		bin := value % signatureSize
+		if value < sig[bin] {
+			sig[bin] = value
+		}
+	}
+	return sig, true
+}
+
+// Similarity 估计两个签名对应文本的相似度（0 到 1）
+func (s Signature) Similarity(other Signature) float64 {
+	same, total := 0, 0
+	for i := range s {
+		if s[i] == emptyBin && other[i] == emptyBin {
+			continue
+		}
+		total++
+		if s[i] == other[i] {
+			same++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(same) / float64(total)
+}
+
+// SimilarPairs 找出相似度不低于 threshold 的所有签名对
+//
+// 先用分段哈希找出候选对，再逐对估计相似度，避免两两比较全部签名。
+func SimilarPairs(sigs []Signature, threshold float64) []Pair {
+	checked := make(map[[2]int]bool)
+	var pairs []Pair
+	for band := 0; band < signatureSize/bandRows; band++ {
+		buckets := make(map[string][]int)
+		key := make([]byte, bandRows*8)
+		for i, sig := range sigs {
+			for row := 0; row < bandRows; row++ {
+				binary.LittleEndian.PutUint64(key[row*8:], sig[band*bandRows+row])
+			}
+			buckets[string(key)] = append(buckets[string(key)], i)
+		}
+
+		for _, members := range buckets {
+			for a := 0; a < len(members); a++ {
+				for b := a + 1; b < len(members); b++ {
+					pair := [2]int{members[a], members[b]}
+					if checked[pair] {
+						continue
+					}
+					checked[pair] = true
+					if similarity := sigs[pair[0]].Similarity(sigs[pair[1]]); similarity >= threshold {
+						pairs = append(pairs, Pair{I: pair[0], J: pair[1], Similarity: similarity})
+					}
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// Normalize 统一大小写并合并空白，使排版差异不影响比较
+func Normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// mix 打散 FNV 哈希的低位，使分桶更均匀（splitmix64 的终结步骤）
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,178 @@
+// Package tags 角色库的标签登记表
+//
+// 角色的用户标签保存在各角色文件夹的附属数据中；这里只记录整个角色库共用的信息：
+// 新建但还没有用到的标签，以及角色卡内嵌标签的改名和隐藏规则。
+package tags
+
+import (
+	"card-manager/internal/pkg/jsonfile"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// maxTagLength 标签的最大字数
+const maxTagLength = 64
+
+// Registry 标签登记表
+type Registry struct {
+	// Tags 用户新建的标签，包括还没有角色使用的
+	Tags []string `json:"tags,omitempty"`
+	// Aliases 角色卡内嵌标签（小写）改用的名称，为空串表示隐藏该标签
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// Store 保存在角色库元数据目录中的标签登记表，读取后缓存在内存中
+//
+// 文件在外部被改动（如撤销操作恢复了旧文件）时重新读取。
+type Store struct {
+	file *jsonfile.Store[Registry]
+}
+
+// New 创建保存在 path 的标签登记表
+func New(path string) *Store {
+	return &Store{file: jsonfile.New(path, (*Registry).clone)}
+}
+
+// Path 标签登记表文件的路径
+func (s *Store) Path() string {
+	return s.file.Path()
+}
+
+// Load 返回标签登记表的副本
+func (s *Store) Load() (*Registry, error) {
+	registry, err := s.file.Load()
+	if err != nil {
+		return &Registry{}, err
+	}
+	return registry, nil
+}
+
+// Update 读取、修改并保存标签登记表
+func (s *Store) Update(modify func(r *Registry) error) error {
+	return s.file.Update(modify)
+}
+
+// clone 复制登记表，避免调用方修改缓存的内容
+func (r *Registry) clone() *Registry {
+	c := &Registry{Tags: append([]string(nil), r.Tags...)}
+	if len(r.Aliases) > 0 {
+		c.Aliases = make(map[string]string, len(r.Aliases))
+		for key, value := range r.Aliases {
+			c.Aliases[key] = value
+		}
+	}
+	return c
+}
+
+// Normalize 整理标签名：去掉首尾空白并把连续空白压成一个空格，返回不能使用的原因
+func Normalize(tag string) (string, string) {
+	tag = strings.Join(strings.Fields(tag), " ")
+	switch {
+	case tag == "":
+		return "", "标签不能为空"
+	case len([]rune(tag)) > maxTagLength:
+		return "", "标签过长"
+	case strings.IndexFunc(tag, unicode.IsControl) >= 0:
+		return "", "标签不能包含控制字符"
+	}
+	return tag, ""
+}
+
+// Contains 判断列表中是否有该标签，不区分大小写
+func Contains(list []string, tag string) bool {
+	return indexOf(list, tag) >= 0
+}
+
+// Merge 合并多个标签列表，不区分大小写去重，保留第一次出现的写法和顺序
+func Merge(lists ...[]string) []string {
+	merged := make([]string, 0)
+	for _, list := range lists {
+		for _, tag := range list {
+			if tag != "" && !Contains(merged, tag) {
+				merged = append(merged, tag)
+			}
+		}
+	}
+	return merged
+}
+
+// Replace 把列表中的 old 换成 tag，tag 为空串时删除；返回新列表和是否有改动
+func Replace(list []string, old, tag string) ([]string, bool) {
+	i := indexOf(list, old)
+	if i < 0 {
+		return list, false
+	}
+	result := append(append([]string(nil), list[:i]...), list[i+1:]...)
+	if tag != "" && !Contains(result, tag) {
+		result = append(result[:i], append([]string{tag}, result[i:]...)...)
+	}
+	return result, true
+}
+
+// indexOf 返回标签在列表中的下标，不区分大小写，找不到时返回 -1
+func indexOf(list []string, tag string) int {
+	for i, item := range list {
+		if strings.EqualFold(item, tag) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Resolve 按改名和隐藏规则整理角色卡内嵌的标签
+func (r *Registry) Resolve(cardTags []string) []string {
+	resolved := make([]string, 0, len(cardTags))
+	for _, tag := range cardTags {
+		tag, reason := Normalize(tag)
+		if reason != "" {
+			continue
+		}
+		if alias, found := r.Aliases[strings.ToLower(tag)]; found {
+			tag = alias
+		}
+		if tag != "" {
+			resolved = append(resolved, tag)
+		}
+	}
+	return Merge(resolved)
+}
+
+// Rename 把标签 old 改为 tag，tag 为空串时删除；内嵌标签通过改名规则跟随
+func (r *Registry) Rename(old, tag string) {
+	r.Tags, _ = Replace(r.Tags, old, tag)
+	if tag != "" && !Contains(r.Tags, tag) {
+		r.Tags = append(r.Tags, tag)
+	}
+	if r.Aliases == nil {
+		r.Aliases = make(map[string]string)
+	}
+	// 已经改名为 old 的内嵌标签一起改名
+	for key, value := range r.Aliases {
+		if strings.EqualFold(value, old) {
+			r.Aliases[key] = tag
+		}
+	}
+	r.Aliases[strings.ToLower(old)] = tag
+	// 改回原名时规则会把标签映射回自身，这样的规则没有作用；其他内嵌标签的改名和隐藏规则保持不变
+	for key, value := range r.Aliases {
+		if key == value {
+			delete(r.Aliases, key)
+		}
+	}
+}
+
+// ErrExists 标签已存在
+var ErrExists = errors.New("标签已存在")
+
+// Create 登记新标签，同名的内嵌标签如果被隐藏则恢复显示
+func (r *Registry) Create(tag string) error {
+	if Contains(r.Tags, tag) {
+		return ErrExists
+	}
+	r.Tags = append(r.Tags, tag)
+	if alias, found := r.Aliases[strings.ToLower(tag)]; found && alias == "" {
+		delete(r.Aliases, strings.ToLower(tag))
+	}
+	return nil
+}
@@ -1,12 +1,8 @@
 package tavern
 
 import (
+	"card-manager/internal/pkg/card"
 	"card-manager/internal/pkg/png"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/hex"
-	"encoding/json"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,6 +16,7 @@ type Scanner struct {
 	importedInternalNames map[string]bool
 	mutex                sync.RWMutex
 	isScanning           bool
+	generation           int
 }
 
 // NewScanner 创建新的Tavern扫描器
@@ -59,31 +56,19 @@ func (s *Scanner) ScanHashes() error {
 			return err
 		}
 		if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".png") {
-			// 计算文件哈希
-			file, err := os.Open(path)
+			// 单次读取同时计算哈希和提取角色卡数据，图像数据只参与哈希计算、不保留在内存中
+			result, err := png.ScanFile(path)
 			if err != nil {
-				return nil // 忽略无法打开的文件
+				return nil // 忽略无法读取的文件
 			}
-			defer file.Close()
-
-			hash := sha256.New()
-			if _, err := io.Copy(hash, file); err != nil {
-				return nil // 忽略无法计算哈希的文件
-			}
-			localHashes[hex.EncodeToString(hash.Sum(nil))] = true
+			localHashes[result.Hash] = true
 
 			// 提取内部名称
-			charaData, err := png.GetInternalCharNameFromPNG(path)
+			charaData, _, err := png.CharacterData(result.Texts)
 			if err == nil {
-				decoded, err := base64.StdEncoding.DecodeString(charaData)
-				if err == nil {
-					var charDataJSON map[string]interface{}
-					if json.Unmarshal(decoded, &charDataJSON) == nil {
-						if name, ok := charDataJSON["name"].(string); ok && name != "" {
-							localInternalNames[name] = true
-						} else if name, ok := charDataJSON["char_name"].(string); ok && name != "" {
-							localInternalNames[name] = true
-						}
+				if parsed, err := card.Decode(charaData); err == nil {
+					if name := parsed.Name(); name != "" {
+						localInternalNames[name] = true
 					}
 				}
 			}
@@ -95,11 +80,19 @@ func (s *Scanner) ScanHashes() error {
 	s.mutex.Lock()
 	s.importedHashes = localHashes
 	s.importedInternalNames = localInternalNames
+	s.generation++
 	s.mutex.Unlock()
 
 	return err
 }
 
+// Generation 导入状态更新的次数，每完成一次扫描加一
+func (s *Scanner) Generation() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.generation
+}
+
 // IsHashImported 检查哈希是否已导入
 func (s *Scanner) IsHashImported(hash string) bool {
 	s.mutex.RLock()
@@ -0,0 +1,331 @@
+package trash
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// infoFileName 每个回收站条目目录中记录原始信息的文件
+const infoFileName = "info.json"
+
+// 回收站条目的类型
+const (
+	KindVersion   = "version"
+	KindStray     = "stray"
+	KindCharacter = "character"
+	KindCategory  = "category"
+	// KindBackup 文件被覆盖前保留的旧内容
+	KindBackup = "backup"
+	// KindUndo 撤销操作时移走的文件
+	KindUndo = "undo"
+)
+
+// ErrNotFound 回收站中没有指定的条目
+var ErrNotFound = errors.New("回收站中没有该条目")
+
+// Item 回收站中的一个条目
+type Item struct {
+	ID           string    `json:"id"`
+	Kind         string    `json:"kind"`
+	Name         string    `json:"name"`
+	OriginalPath string    `json:"originalPath"`
+	DeletedAt    time.Time `json:"deletedAt"`
+	Size         int64     `json:"size"`
+}
+
+// Bin 角色库的回收站
+//
+// 每个被删除的文件或文件夹连同记录原始路径和删除时间的 info.json
+// 一起存放在以条目 ID 命名的子目录中。
+type Bin struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+// New 创建位于 dir 的回收站，目录在第一次删除时创建
+func New(dir string) *Bin {
+	return &Bin{dir: dir}
+}
+
+// Dir 回收站目录
+func (b *Bin) Dir() string {
+	return b.dir
+}
+
+// Trash 将文件或文件夹移入回收站
+func (b *Bin) Trash(path, kind string) (*Item, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	stats, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	itemDir := filepath.Join(b.dir, id)
+	if err := os.MkdirAll(itemDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建回收站目录失败: %w", err)
+	}
+
+	item := &Item{
+		ID:           id,
+		Kind:         kind,
+		Name:         filepath.Base(path),
+		OriginalPath: path,
+		DeletedAt:    time.Now(),
+		Size:         pathSize(path, stats),
+	}
+	if err := writeInfo(itemDir, item); err != nil {
+		os.RemoveAll(itemDir)
+		return nil, err
+	}
+	if err := os.Rename(path, filepath.Join(itemDir, item.Name)); err != nil {
+		os.RemoveAll(itemDir)
+		return nil, err
+	}
+	return item, nil
+}
+
+// Backup 将文件复制一份到回收站，原文件保持不变，用于在覆盖前保留旧内容
+func (b *Bin) Backup(path string) (*Item, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	stats, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if stats.IsDir() {
+		return nil, fmt.Errorf("只能备份文件: %s", path)
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	itemDir := filepath.Join(b.dir, id)
+	if err := os.MkdirAll(itemDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建回收站目录失败: %w", err)
+	}
+
+	item := &Item{
+		ID:           id,
+		Kind:         KindBackup,
+		Name:         filepath.Base(path),
+		OriginalPath: path,
+		DeletedAt:    time.Now(),
+		Size:         stats.Size(),
+	}
+	if err := writeInfo(itemDir, item); err != nil {
+		os.RemoveAll(itemDir)
+		return nil, err
+	}
+	if err := copyFile(path, filepath.Join(itemDir, item.Name), stats); err != nil {
+		os.RemoveAll(itemDir)
+		return nil, err
+	}
+	return item, nil
+}
+
+// Get 读取回收站条目的信息
+func (b *Bin) Get(id string) (*Item, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.get(id)
+}
+
+// List 列出回收站中的所有条目，最近删除的在前
+func (b *Bin) List() ([]Item, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.list()
+}
+
+// Restore 将条目移回原来的位置，原位置已被占用时追加 _1、_2 等后缀，返回恢复后的路径
+func (b *Bin) Restore(id string) (string, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	item, err := b.get(id)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(item.OriginalPath), 0755); err != nil {
+		return "", fmt.Errorf("创建原目录失败: %w", err)
+	}
+	source := filepath.Join(b.dir, id, item.Name)
+	stats, err := os.Stat(source)
+	if err != nil {
+		return "", err
+	}
+	target := availablePath(item.OriginalPath, stats.IsDir())
+	if err := os.Rename(source, target); err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(filepath.Join(b.dir, id)); err != nil {
+		return target, err
+	}
+	return target, nil
+}
+
+// Purge 永久删除条目
+func (b *Bin) Purge(id string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, err := b.get(id); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(b.dir, id))
+}
+
+// PurgeBefore 永久删除在 cutoff 之前移入回收站的条目，返回删除的条目
+func (b *Bin) PurgeBefore(cutoff time.Time) ([]Item, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	items, err := b.list()
+	if err != nil {
+		return nil, err
+	}
+	purged := make([]Item, 0)
+	for _, item := range items {
+		if !item.DeletedAt.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(b.dir, item.ID)); err != nil {
+			return purged, err
+		}
+		purged = append(purged, item)
+	}
+	return purged, nil
+}
+
+func (b *Bin) list() ([]Item, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Item{}, nil
+		}
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		item, err := b.get(entry.Name())
+		if err != nil {
+			continue
+		}
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// get 读取条目信息，ID 不合法或条目不存在时返回 ErrNotFound
+func (b *Bin) get(id string) (*Item, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(b.dir, id, infoFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var item Item
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("回收站条目信息已损坏: %w", err)
+	}
+	item.ID = id
+	return &item, nil
+}
+
+// copyFile 复制文件并保留修改时间
+func copyFile(src, dst string, stats os.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, stats.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, stats.ModTime(), stats.ModTime())
+}
+
+func writeInfo(itemDir string, item *Item) error {
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(itemDir, infoFileName), data, 0644)
+}
+
+// newID 生成按删除时间排序的条目 ID
+func newID() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix), nil
+}
+
+// pathSize 文件的大小，文件夹为其中所有文件的大小之和
+func pathSize(path string, stats os.FileInfo) int64 {
+	if !stats.IsDir() {
+		return stats.Size()
+	}
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// availablePath 路径已存在时追加 _1、_2 等后缀，文件的后缀加在扩展名之前
+func availablePath(path string, isDir bool) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+	ext := ""
+	if !isDir {
+		ext = filepath.Ext(path)
+	}
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s_%d%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
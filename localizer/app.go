@@ -1,7 +1,8 @@
 package localizer
 
 import (
-	"encoding/base64"
+	"card-manager/internal/pkg/card"
+	"card-manager/internal/pkg/png"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -72,16 +73,21 @@ func runInternal(opts Options, sendMessage func(msgType, content string)) (bool,
 	}
 
 	// 1. 从 PNG 加载角色卡数据
-	base64Data, err := GetCharacterData(opts.CardPath)
+	base64Data, err := png.GetCharacterData(opts.CardPath)
 	if err != nil {
 		return false, logBuilder.String(), fmt.Errorf("从 %s 读取角色卡数据时出错: %v", opts.CardPath, err)
 	}
 
-	jsonData, err := base64.StdEncoding.DecodeString(base64Data)
+	parsedCard, err := card.Decode(base64Data)
 	if err != nil {
-		return false, logBuilder.String(), fmt.Errorf("解码 base64 数据时出错: %v", err)
+		return false, logBuilder.String(), fmt.Errorf("解析角色卡数据时出错: %v", err)
 	}
 
+	// 本地化过程按通用 JSON 结构遍历和替换链接
+	jsonData, err := json.Marshal(parsedCard)
+	if err != nil {
+		return false, logBuilder.String(), fmt.Errorf("序列化角色卡数据时出错: %v", err)
+	}
 	var cardData map[string]interface{}
 	if err := json.Unmarshal(jsonData, &cardData); err != nil {
 		return false, logBuilder.String(), fmt.Errorf("解析 json 数据时出错: %v", err)
@@ -129,7 +135,7 @@ func runInternal(opts Options, sendMessage func(msgType, content string)) (bool,
 
 	logWriter("开始本地化处理...")
 
-	charName, _ := cardData["name"].(string)
+	charName := parsedCard.Name()
 	if charName == "" {
 		charName = strings.TrimSuffix(filepath.Base(opts.CardPath), filepath.Ext(opts.CardPath))
 	}
@@ -168,26 +174,18 @@ func runInternal(opts Options, sendMessage func(msgType, content string)) (bool,
 		return false, logBuilder.String(), fmt.Errorf("本地化过程失败: %v", err)
 	}
 
-	v2CardData := make(map[string]interface{})
-	for k, v := range updatedCardData {
-		if k != "spec" && k != "spec_version" {
-			v2CardData[k] = v
-		}
+	updatedBytes, err := json.Marshal(updatedCardData)
+	if err != nil {
+		return false, logBuilder.String(), fmt.Errorf("序列化本地化数据失败: %v", err)
 	}
-	v2Bytes, err := json.Marshal(v2CardData)
+	updatedCard, err := card.Parse(updatedBytes)
 	if err != nil {
-		return false, logBuilder.String(), fmt.Errorf("序列化 V2 数据失败: %v", err)
+		return false, logBuilder.String(), fmt.Errorf("解析本地化数据失败: %v", err)
 	}
-	v2Base64 := base64.StdEncoding.EncodeToString(v2Bytes)
-
-	v3CardData := updatedCardData
-	v3CardData["spec"] = "chara_card_v3"
-	v3CardData["spec_version"] = "3.0"
-	v3Bytes, err := json.Marshal(v3CardData)
+	v2Base64, v3Base64, err := updatedCard.EncodeChunks()
 	if err != nil {
-		return false, logBuilder.String(), fmt.Errorf("序列化 V3 数据失败: %v", err)
+		return false, logBuilder.String(), err
 	}
-	v3Base64 := base64.StdEncoding.EncodeToString(v3Bytes)
 
 	cardOutputDir := filepath.Join(filepath.Dir(opts.CardPath), "本地化")
 	if err := os.MkdirAll(cardOutputDir, os.ModePerm); err != nil {
@@ -195,7 +193,7 @@ func runInternal(opts Options, sendMessage func(msgType, content string)) (bool,
 	}
 	finalCardPath := filepath.Join(cardOutputDir, filepath.Base(opts.CardPath))
 
-	err = WriteCharacterData(opts.CardPath, finalCardPath, v2Base64, v3Base64)
+	err = png.WriteCharacterData(opts.CardPath, finalCardPath, v2Base64, v3Base64)
 	if err != nil {
 		return false, logBuilder.String(), fmt.Errorf("写入新角色卡失败: %v", err)
 	}
@@ -1,5 +0,0 @@
-module github.com/yzlmx/character-card-manager/cli
-
-go 1.23.4
-
-require gopkg.in/yaml.v3 v3.0.1
@@ -184,6 +184,10 @@ versionListElement.addEventListener('click', (event) => {
     if (!target) return;
     if (event.target.classList.contains('delete-btn')) {
         handleDeleteVersion(event.target.dataset.filepath);
+    } else if (event.target.classList.contains('note-btn')) {
+        handleVersionNote(event.target.dataset);
+    } else if (event.target.classList.contains('current-btn')) {
+        handleCurrentVersion(event.target.dataset);
     } else {
         updateDetailsPreview(target.dataset.imagepath);
         versionListElement.querySelectorAll('.version-list-item').forEach(el => el.classList.remove('active'));
@@ -482,6 +486,14 @@ function updateFaceCharDatalist() {
 
 function updateDetailsPreview(imagePath) { document.getElementById('details-preview-img').src = `${SERVER_URL}/api/image?path=${encodeURIComponent(imagePath)}`; }
 
+// createTextElement 创建元素并以 textContent 写入文本，用于显示用户输入的内容
+function createTextElement(tag, className, text) {
+    const el = document.createElement(tag);
+    if (className) el.className = className;
+    if (text !== undefined) el.textContent = text;
+    return el;
+}
+
 function showDetails(folderPath) {
     const card = allCardsData[folderPath];
     if (!card) {
@@ -500,13 +512,40 @@ function showDetails(folderPath) {
     faceGrid.innerHTML = '';
 
     // --- Version List ---
+    // 标签、备注等由用户输入，全部通过 textContent 和 dataset 写入，避免被当作 HTML 解析
     versionListElement.innerHTML = '';
     card.versions.forEach((v, index) => {
         const item = document.createElement('li');
         item.className = 'version-list-item';
         if (v.path === card.latestVersionPath) item.classList.add('active');
         item.dataset.imagepath = v.path;
-        item.innerHTML = `<div class="version-item-info"><strong>${v.fileName}</strong><small>${v.path}</small></div><button class="delete-btn" data-filepath="${v.path}">删除</button>`;
+        const info = createTextElement('div', 'version-item-info');
+        const title = createTextElement('strong', '', v.fileName);
+        if (v.label) title.appendChild(createTextElement('span', 'version-label', v.label));
+        const pinned = v.isCurrent && card.currentPinned;
+        if (pinned) title.appendChild(createTextElement('span', 'version-label current-mark', '📌 当前'));
+        info.appendChild(title);
+        info.appendChild(createTextElement('small', '', v.path));
+        if (v.comment) info.appendChild(createTextElement('small', 'version-comment', v.comment));
+        if (v.problems && v.problems.length) {
+            info.appendChild(createTextElement('small', 'version-problems', `⚠️ 文件损坏: ${v.problems.join(', ')}`));
+        }
+        item.appendChild(info);
+
+        const currentBtn = createTextElement('button', 'current-btn', pinned ? '取消指定' : '设为当前');
+        currentBtn.dataset.folderpath = card.folderPath;
+        currentBtn.dataset.filepath = pinned ? '' : v.path;
+        item.appendChild(currentBtn);
+
+        const noteBtn = createTextElement('button', 'note-btn', '标签');
+        noteBtn.dataset.filepath = v.path;
+        noteBtn.dataset.label = v.label || '';
+        noteBtn.dataset.comment = v.comment || '';
+        item.appendChild(noteBtn);
+
+        const deleteBtn = createTextElement('button', 'delete-btn', '删除');
+        deleteBtn.dataset.filepath = v.path;
+        item.appendChild(deleteBtn);
         versionListElement.appendChild(item);
     });
 
@@ -586,6 +625,20 @@ function showDetails(folderPath) {
     mergeBtn.onclick = () => showMergeModal(card.folderPath);
     actionsContainer.appendChild(mergeBtn);
 
+    const renameBtn = document.createElement('button');
+    renameBtn.id = 'details-rename-btn';
+    renameBtn.className = 'styled-btn primary';
+    renameBtn.textContent = '重命名';
+    renameBtn.onclick = () => handleRenameCharacter(card);
+    actionsContainer.appendChild(renameBtn);
+
+    const deleteCharacterBtn = document.createElement('button');
+    deleteCharacterBtn.id = 'details-delete-character-btn';
+    deleteCharacterBtn.className = 'styled-btn danger';
+    deleteCharacterBtn.textContent = '删除角色';
+    deleteCharacterBtn.onclick = () => handleDeleteCharacter(card.folderPath, card.name);
+    actionsContainer.appendChild(deleteCharacterBtn);
+
     // --- Show Modal ---
     openModal('details-modal');
 }
@@ -696,7 +749,7 @@ async function handleOpenFolder(folderPath) {
 
 async function handleDeleteVersion(filePath) {
     const fileName = filePath.substring(filePath.lastIndexOf(/[\\\/]/) + 1);
-    showCustomConfirm('删除文件', `确定要删除文件: ${fileName} 吗？\n此操作不可恢复！`, async () => {
+    showCustomConfirm('删除文件', `确定要删除文件: ${fileName} 吗？\n文件将移入回收站，可在保留期内恢复。`, async () => {
         try {
             const response = await fetch(`${SERVER_URL}/api/delete-version`, { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ filePath }) });
             const result = await response.json();
@@ -712,6 +765,75 @@ async function handleDeleteVersion(filePath) {
     });
 }
 
+async function handleDeleteCharacter(folderPath, characterName) {
+    showCustomConfirm('删除角色', `确定要删除角色 '${characterName}' 及其所有版本吗？\n角色文件夹将移入回收站，可在保留期内恢复。`, async () => {
+        try {
+            const response = await fetch(`${SERVER_URL}/api/delete-character`, { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ folderPath }) });
+            const result = await response.json();
+
+            if (result.success) {
+                logMessage(result.message || '角色已删除', 'success');
+                closeModal('details-modal');
+                fetchCards();
+            } else {
+                logMessage(result.error || '删除角色失败', 'error');
+            }
+        } catch (error) { logMessage('删除角色请求失败', 'error', error.message); }
+    });
+}
+
+async function handleRenameCharacter(card) {
+    const newName = prompt('新的角色名称:', card.name);
+    if (newName === null || newName.trim() === '') return;
+    const renameInternal = confirm('是否同时把角色卡内的名称改为新名称？\n会另存为一个新版本，原版本保持不变。');
+    try {
+        const response = await fetch(`${SERVER_URL}/api/rename-character`, { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ folderPath: card.folderPath, newName: newName.trim(), renameInternal }) });
+        const result = await response.json();
+
+        if (result.success) {
+            logMessage(result.message || '角色已重命名', 'success');
+            closeModal('details-modal');
+            fetchCards();
+        } else {
+            logMessage(result.message || '重命名失败', 'error');
+        }
+    } catch (error) { logMessage('重命名请求失败', 'error', error.message); }
+}
+
+async function handleVersionNote({ filepath, label, comment }) {
+    const newLabel = prompt('版本标签（如 "官方 v2"、"我的修改"，留空清除）:', label || '');
+    if (newLabel === null) return;
+    const newComment = prompt('版本备注（可留空）:', comment || '');
+    if (newComment === null) return;
+    try {
+        const response = await fetch(`${SERVER_URL}/api/version-note`, { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ versionPath: filepath, label: newLabel, comment: newComment }) });
+        const result = await response.json();
+
+        if (result.success) {
+            logMessage(result.message || '版本备注已保存', 'success');
+            closeModal('details-modal');
+            fetchCards();
+        } else {
+            logMessage(result.error || '保存版本备注失败', 'error');
+        }
+    } catch (error) { logMessage('保存版本备注请求失败', 'error', error.message); }
+}
+
+async function handleCurrentVersion({ folderpath, filepath }) {
+    try {
+        const response = await fetch(`${SERVER_URL}/api/current-version`, { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ folderPath: folderpath, versionPath: filepath }) });
+        const result = await response.json();
+
+        if (result.success) {
+            logMessage(result.message || '当前版本已设置', 'success');
+            closeModal('details-modal');
+            fetchCards();
+        } else {
+            logMessage(result.error || '设置当前版本失败', 'error');
+        }
+    } catch (error) { logMessage('设置当前版本请求失败', 'error', error.message); }
+}
+
 async function handleMove(oldFolderPath) {
     const newCategory = document.getElementById('details-category-select').value;
     if (!newCategory) { showToast('请选择一个目标分类！', 'error'); return; }
@@ -806,7 +928,7 @@ function handleOrganize(strayPath) {
 
     deleteBtn.onclick = () => {
         const fileName = strayPath.substring(strayPath.lastIndexOf(/[\\\/]/) + 1);
-        showCustomConfirm('删除文件', `确定要永久删除待整理文件: ${fileName} 吗？\n此操作不可恢复！`, async () => {
+        showCustomConfirm('删除文件', `确定要删除待整理文件: ${fileName} 吗？\n文件将移入回收站，可在保留期内恢复。`, async () => {
             try {
                 const response = await fetch(`${SERVER_URL}/api/delete-stray`, { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ filePath: strayPath }) });
                 const result = await response.json();